@@ -0,0 +1,90 @@
+package gormx
+
+import (
+	"gorm.io/gorm"
+)
+
+// CallbackPosition 回调相对于 gorm 操作的执行位置
+type CallbackPosition int
+
+const (
+	// CallbackBefore 在执行 SQL 之前调用，可以修改要写入的字段
+	CallbackBefore CallbackPosition = iota
+	// CallbackAfter 在执行 SQL 之后调用，可以通过 db.Error 获取执行结果
+	CallbackAfter
+)
+
+type register func(name string, fn func(*gorm.DB)) error
+
+// RegisterCreateCallback 注册 create 操作的全局回调，对所有克隆出来的 Gormx 都生效，name 不能重复
+func (s *Gormx) RegisterCreateCallback(name string, position CallbackPosition, fn func(db *gorm.DB)) error {
+	cb := s.db.Callback().Create()
+	return registerAt(position, cb.Before("gorm:create").Register, cb.After("gorm:create").Register)(name, fn)
+}
+
+// RegisterQueryCallback 注册 query 操作的全局回调，对所有克隆出来的 Gormx 都生效，name 不能重复
+func (s *Gormx) RegisterQueryCallback(name string, position CallbackPosition, fn func(db *gorm.DB)) error {
+	cb := s.db.Callback().Query()
+	return registerAt(position, cb.Before("gorm:query").Register, cb.After("gorm:query").Register)(name, fn)
+}
+
+// RegisterUpdateCallback 注册 update 操作的全局回调，对所有克隆出来的 Gormx 都生效，name 不能重复
+func (s *Gormx) RegisterUpdateCallback(name string, position CallbackPosition, fn func(db *gorm.DB)) error {
+	cb := s.db.Callback().Update()
+	return registerAt(position, cb.Before("gorm:update").Register, cb.After("gorm:update").Register)(name, fn)
+}
+
+// RegisterDeleteCallback 注册 delete 操作的全局回调，对所有克隆出来的 Gormx 都生效，name 不能重复
+func (s *Gormx) RegisterDeleteCallback(name string, position CallbackPosition, fn func(db *gorm.DB)) error {
+	cb := s.db.Callback().Delete()
+	return registerAt(position, cb.Before("gorm:delete").Register, cb.After("gorm:delete").Register)(name, fn)
+}
+
+// RemoveCallback 从 create、query、update、delete 操作中移除名称为 name 的回调
+func (s *Gormx) RemoveCallback(name string) error {
+	cb := s.db.Callback()
+	for _, p := range []interface {
+		Get(name string) func(*gorm.DB)
+		Remove(name string) error
+	}{cb.Create(), cb.Query(), cb.Update(), cb.Delete()} {
+		if p.Get(name) == nil {
+			continue
+		}
+		if err := p.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func registerAt(position CallbackPosition, before, after register) register {
+	if position == CallbackAfter {
+		return after
+	}
+	return before
+}
+
+// RegisterAround 在 create、query、update、delete、row、raw 操作的执行前后注册回调，name 用于区分回调名称
+// before、after 根据操作名称返回对应的回调，用于实现 gorm.Plugin
+func RegisterAround(db *gorm.DB, name string, before, after func(operation string) func(*gorm.DB)) error {
+	cb := db.Callback()
+	for _, c := range []struct {
+		operation     string
+		before, after register
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	} {
+		if err := c.before("gormx:"+name+"_before_"+c.operation, before(c.operation)); err != nil {
+			return err
+		}
+		if err := c.after("gormx:"+name+"_after_"+c.operation, after(c.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package gormx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// 以下方法与对应的不带 Context 后缀的方法相同，只是显式传入上下文，等同于先调用 WithContext
+
+func (s *Gormx) TxContext(ctx context.Context, fn func(tx *Gormx) error, opts ...*sql.TxOptions) error {
+	return s.WithContext(ctx).Tx(fn, opts...)
+}
+
+func (s *Gormx) TxSafeContext(ctx context.Context, fn func(tx *Gormx) error, opts ...*sql.TxOptions) error {
+	return s.WithContext(ctx).TxSafe(fn, opts...)
+}
+
+func (s *Gormx) TxWithRetryContext(ctx context.Context, fn func(tx *Gormx) error, maxRetries int, opts ...*sql.TxOptions) error {
+	return s.WithContext(ctx).TxWithRetry(fn, maxRetries, opts...)
+}
+
+func (s *Gormx) InsertContext(ctx context.Context, doc interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Insert(doc, opts...)
+}
+
+func (s *Gormx) InsertNContext(ctx context.Context, doc interface{}, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).InsertN(doc, opts...)
+}
+
+func (s *Gormx) InsertIgnoreContext(ctx context.Context, doc interface{}, conflictColumns ...string) (bool, error) {
+	return s.WithContext(ctx).InsertIgnore(doc, conflictColumns...)
+}
+
+func (s *Gormx) InsertInBatchesContext(ctx context.Context, docs interface{}, batchSize int, opts ...Option) error {
+	return s.WithContext(ctx).InsertInBatches(docs, batchSize, opts...)
+}
+
+func (s *Gormx) UpsertContext(ctx context.Context, doc interface{}, conflictColumns []string, updateColumns []string, opts ...Option) error {
+	return s.WithContext(ctx).Upsert(doc, conflictColumns, updateColumns, opts...)
+}
+
+func (s *Gormx) UpsertReturningContext(ctx context.Context, doc interface{}, conflictColumns []string, updateColumns []string) error {
+	return s.WithContext(ctx).UpsertReturning(doc, conflictColumns, updateColumns)
+}
+
+func (s *Gormx) UpsertWhereContext(ctx context.Context, doc interface{}, conflictColumns []string, updateColumns []string, where string, args ...interface{}) error {
+	return s.WithContext(ctx).UpsertWhere(doc, conflictColumns, updateColumns, where, args...)
+}
+
+func (s *Gormx) UpsertManyContext(ctx context.Context, docs interface{}, conflictColumns []string, updateColumns []string, batchSize int, opts ...Option) error {
+	return s.WithContext(ctx).UpsertMany(docs, conflictColumns, updateColumns, batchSize, opts...)
+}
+
+func (s *Gormx) SaveContext(ctx context.Context, doc interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Save(doc, opts...)
+}
+
+func (s *Gormx) SaveNContext(ctx context.Context, doc interface{}, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).SaveN(doc, opts...)
+}
+
+func (s *Gormx) FindOneContext(ctx context.Context, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).FindOne(dest, opts...)
+}
+
+func (s *Gormx) FindLastContext(ctx context.Context, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).FindLast(dest, opts...)
+}
+
+func (s *Gormx) TakeContext(ctx context.Context, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Take(dest, opts...)
+}
+
+func (s *Gormx) FindOneOptionalContext(ctx context.Context, dest interface{}, opts ...Option) (bool, error) {
+	return s.WithContext(ctx).FindOneOptional(dest, opts...)
+}
+
+func (s *Gormx) FindManyContext(ctx context.Context, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).FindMany(dest, opts...)
+}
+
+func (s *Gormx) FindPageContext(ctx context.Context, dest interface{}, page, size int, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).FindPage(dest, page, size, opts...)
+}
+
+func (s *Gormx) FirstOrCreateContext(ctx context.Context, dest interface{}, opts ...Option) (bool, error) {
+	return s.WithContext(ctx).FirstOrCreate(dest, opts...)
+}
+
+func (s *Gormx) FirstOrInitContext(ctx context.Context, dest interface{}, opts ...Option) (bool, error) {
+	return s.WithContext(ctx).FirstOrInit(dest, opts...)
+}
+
+func (s *Gormx) FindInBatchesContext(ctx context.Context, dest interface{}, batchSize int, fn func(tx *Gormx, batch int) error, opts ...Option) error {
+	return s.WithContext(ctx).FindInBatches(dest, batchSize, fn, opts...)
+}
+
+func (s *Gormx) IterateContext(ctx context.Context, fn func(scan func(dest interface{}) error) error, opts ...Option) error {
+	return s.WithContext(ctx).Iterate(fn, opts...)
+}
+
+func (s *Gormx) PluckContext(ctx context.Context, column string, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Pluck(column, dest, opts...)
+}
+
+func (s *Gormx) PluckManyContext(ctx context.Context, columns []string, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).PluckMany(columns, dest, opts...)
+}
+
+func (s *Gormx) CountContext(ctx context.Context, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).Count(opts...)
+}
+
+func (s *Gormx) CountWithDeletedContext(ctx context.Context, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).CountWithDeleted(opts...)
+}
+
+func (s *Gormx) CountDistinctContext(ctx context.Context, column string, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).CountDistinct(column, opts...)
+}
+
+func (s *Gormx) SumContext(ctx context.Context, column string, opts ...Option) (float64, error) {
+	return s.WithContext(ctx).Sum(column, opts...)
+}
+
+func (s *Gormx) AvgContext(ctx context.Context, column string, opts ...Option) (float64, error) {
+	return s.WithContext(ctx).Avg(column, opts...)
+}
+
+func (s *Gormx) MaxContext(ctx context.Context, column string, opts ...Option) (float64, error) {
+	return s.WithContext(ctx).Max(column, opts...)
+}
+
+func (s *Gormx) MinContext(ctx context.Context, column string, opts ...Option) (float64, error) {
+	return s.WithContext(ctx).Min(column, opts...)
+}
+
+func (s *Gormx) ExistsContext(ctx context.Context, dest interface{}, opts ...Option) (bool, error) {
+	return s.WithContext(ctx).Exists(dest, opts...)
+}
+
+func (s *Gormx) UpdatesContext(ctx context.Context, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Updates(dest, opts...)
+}
+
+func (s *Gormx) UpdatesNContext(ctx context.Context, dest interface{}, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).UpdatesN(dest, opts...)
+}
+
+func (s *Gormx) UpdateColumnsContext(ctx context.Context, values interface{}, opts ...Option) error {
+	return s.WithContext(ctx).UpdateColumns(values, opts...)
+}
+
+func (s *Gormx) UpdateBatchContext(ctx context.Context, docs interface{}, columns []string) error {
+	return s.WithContext(ctx).UpdateBatch(docs, columns)
+}
+
+func (s *Gormx) UpdateContext(ctx context.Context, column string, value interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Update(column, value, opts...)
+}
+
+func (s *Gormx) UpdateNContext(ctx context.Context, column string, value interface{}, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).UpdateN(column, value, opts...)
+}
+
+func (s *Gormx) IncrementContext(ctx context.Context, column string, delta interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Increment(column, delta, opts...)
+}
+
+func (s *Gormx) DecrementContext(ctx context.Context, column string, delta interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Decrement(column, delta, opts...)
+}
+
+func (s *Gormx) DeleteContext(ctx context.Context, dest interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Delete(dest, opts...)
+}
+
+func (s *Gormx) DeleteNContext(ctx context.Context, dest interface{}, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).DeleteN(dest, opts...)
+}
+
+func (s *Gormx) DeleteWhereContext(ctx context.Context, model interface{}, opts ...Option) (int64, error) {
+	return s.WithContext(ctx).DeleteWhere(model, opts...)
+}
+
+func (s *Gormx) RestoreContext(ctx context.Context, model interface{}, opts ...Option) error {
+	return s.WithContext(ctx).Restore(model, opts...)
+}
+
+func (s *Gormx) RawCountContext(ctx context.Context, sql string, values ...interface{}) (int64, error) {
+	return s.WithContext(ctx).RawCount(sql, values...)
+}
+
+func (s *Gormx) ExecContext(ctx context.Context, sql string, values ...interface{}) error {
+	return s.WithContext(ctx).Exec(sql, values...)
+}
+
+func (s *Gormx) ScanContext(ctx context.Context, dest interface{}) error {
+	return s.WithContext(ctx).Scan(dest)
+}
+
+func (s *Gormx) AutoMigrateContext(ctx context.Context, models ...interface{}) error {
+	return s.WithContext(ctx).AutoMigrate(models...)
+}
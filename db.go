@@ -5,13 +5,36 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
 )
 
+const (
+	defaultBatchSize = 100
+	defaultTxBackoff = 10 * time.Millisecond
+	// defaultConnectRetryInterval 连接失败重试的默认间隔时间
+	defaultConnectRetryInterval = time.Second
+	// 连接池的配置都为 0 时使用的默认值，MaxLifetime 单位为秒
+	defaultMaxIdleConn = 10
+	defaultMaxOpenConn = 100
+	defaultMaxLifetime = 3600
+)
+
+// isRetryable 事务重试时判断错误是否可以重试，测试时可以替换
+var isRetryable = IsRetryable
+
 var (
 	ErrNoRowsAffected = errors.New("no rows affected")
+	ErrNotFound       = gorm.ErrRecordNotFound
+	// ErrTxPanic TxSafe 的回调发生 panic 时返回的错误
+	ErrTxPanic = errors.New("transaction panicked")
 )
 
 type Config struct {
@@ -20,15 +43,171 @@ type Config struct {
 	MaxOpenConn int
 	MaxLifetime int64
 	Debug       bool
+	// ConnectRetries 连接失败时的重试次数，默认不重试
+	ConnectRetries int
+	// ConnectRetryInterval 第一次重试前的等待时间，之后每次翻倍，默认为 1s
+	ConnectRetryInterval time.Duration
+	// Logger 自定义日志，Debug 为 true 时日志级别会调整为 Info
+	Logger logger.Interface
+	// SlowThreshold 慢查询阈值，超过时输出警告日志，默认为 200ms，小于 0 时关闭
+	// 使用自定义日志时只有大于 0 才会输出慢查询日志
+	SlowThreshold time.Duration
+	// TablePrefix 表名前缀，实现了 TableName 方法的模型同样会添加前缀，通过 Table 指定的表名和 Migrator 不会添加
+	TablePrefix string
+	// SingularTable 表名使用单数形式，如 User 对应 user 而不是 users
+	SingularTable bool
+	// DisableForeignKeyConstraintWhenMigrating AutoMigrate 时不创建外键约束
+	DisableForeignKeyConstraintWhenMigrating bool
+	// PrepareStmt 缓存预编译语句，相同的 SQL 只预编译一次
+	// 事务中会复用缓存的语句并绑定到事务的连接上，事务结束后缓存仍然有效
+	PrepareStmt bool
+	// SkipDefaultTransaction 写操作不再默认开启事务，可以提升写入性能
+	SkipDefaultTransaction bool
+	// Replicas 只读副本，配置后 FindOne、FindMany、Count、Pluck 等读操作会使用副本，写操作使用主库
+	Replicas []gorm.Dialector
+	// QueryTimeout 单条语句的超时时间，上下文已经设置截止时间时不生效
+	QueryTimeout time.Duration
+	// StatementTimeout 服务端的语句超时时间，新建连接时设置，超时的语句由服务端取消
+	// Dialector 和 Replicas 需要实现 StatementTimeoutDialector，例如 postgresx.Open 创建的 Dialector
+	StatementTimeout time.Duration
+	// Context 默认的上下文，没有通过 WithContext 设置上下文时使用，上下文取消后所有操作都会失败
+	Context context.Context
+	// Plugins 在 New 时通过 gorm 的 Use 注册的插件，例如 metrics.New() 统计 Prometheus 指标，tracing.New(nil) 链路追踪
+	Plugins []gorm.Plugin
+	// DefaultPageSize 分页时默认的每页数量，默认为 20
+	DefaultPageSize int
+	// MaxPageSize 分页时每页的最大数量，默认为 100
+	MaxPageSize int
+}
+
+func (c *Config) pageSizes() (int, int) {
+	defaultSize, maxSize := defaultPageSize, maxPageSize
+	if c == nil {
+		return defaultSize, maxSize
+	}
+	if c.DefaultPageSize > 0 {
+		defaultSize = c.DefaultPageSize
+	}
+	if c.MaxPageSize > 0 {
+		maxSize = c.MaxPageSize
+	}
+	return defaultSize, maxSize
 }
 
 type Gormx struct {
 	cfg *Config
 	db  *gorm.DB
+	// scopes 默认条件，会在每次操作的 opts 之前应用
+	scopes []Option
+	// registry 通过 RegisterScope 注册的命名条件
+	registry *scopeRegistry
+}
+
+// validate 检查连接池配置，为 0 表示不设置
+func (c *Config) validate() error {
+	if c.MaxIdleConn < 0 || c.MaxOpenConn < 0 || c.MaxLifetime < 0 {
+		return errors.New("MaxIdleConn, MaxOpenConn and MaxLifetime should not be negative")
+	}
+	if c.StatementTimeout < 0 {
+		return fmt.Errorf("StatementTimeout %s should not be negative", c.StatementTimeout)
+	}
+	if c.MaxOpenConn > 0 && c.MaxIdleConn > c.MaxOpenConn {
+		return fmt.Errorf("MaxIdleConn %d should not be greater than MaxOpenConn %d", c.MaxIdleConn, c.MaxOpenConn)
+	}
+	return nil
+}
+
+// applyPool 设置连接池，配置都为 0 时使用默认值
+func (c *Config) applyPool(sqlDb *sql.DB) {
+	if c.MaxIdleConn == 0 && c.MaxOpenConn == 0 && c.MaxLifetime == 0 {
+		sqlDb.SetMaxIdleConns(defaultMaxIdleConn)
+		sqlDb.SetMaxOpenConns(defaultMaxOpenConn)
+		sqlDb.SetConnMaxLifetime(defaultMaxLifetime * time.Second)
+		return
+	}
+
+	if c.MaxIdleConn > 0 {
+		sqlDb.SetMaxIdleConns(c.MaxIdleConn)
+	}
+
+	if c.MaxOpenConn > 0 {
+		sqlDb.SetMaxOpenConns(c.MaxOpenConn)
+	}
+
+	if c.MaxLifetime > 0 {
+		sqlDb.SetConnMaxLifetime(time.Duration(c.MaxLifetime) * time.Second)
+	}
+}
+
+func (c *Config) apply(conf *gorm.Config) {
+	switch {
+	case c.Logger != nil && c.SlowThreshold > 0:
+		conf.Logger = slowLogger{Interface: c.Logger, threshold: c.SlowThreshold}
+	case c.Logger != nil:
+		conf.Logger = c.Logger
+	case c.SlowThreshold < 0:
+		conf.Logger = newLogger(0)
+	case c.SlowThreshold > 0:
+		conf.Logger = newLogger(c.SlowThreshold)
+	}
+	if c.TablePrefix != "" || c.SingularTable {
+		conf.NamingStrategy = schema.NamingStrategy{
+			TablePrefix:   c.TablePrefix,
+			SingularTable: c.SingularTable,
+		}
+	}
+	if c.DisableForeignKeyConstraintWhenMigrating {
+		conf.DisableForeignKeyConstraintWhenMigrating = true
+	}
+	if c.PrepareStmt {
+		conf.PrepareStmt = true
+	}
+	if c.SkipDefaultTransaction {
+		conf.SkipDefaultTransaction = true
+	}
+	if c.Debug {
+		if conf.Logger == nil {
+			conf.Logger = newLogger(defaultSlowThreshold)
+		}
+		conf.Logger = conf.Logger.LogMode(logger.Info)
+	}
+}
+
+// configOption 将 Config 中的配置应用到 gorm.Config，会在 New 传入的 gorm.Option 之后执行
+type configOption struct {
+	cfg *Config
+}
+
+func (o configOption) Apply(conf *gorm.Config) error {
+	o.cfg.apply(conf)
+	return nil
+}
+
+func (o configOption) AfterInitialize(*gorm.DB) error {
+	return nil
 }
 
 func New(cfg *Config, opts ...gorm.Option) (*Gormx, error) {
-	db, err := gorm.Open(cfg.Dialector, opts...)
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config, %w", err)
+	}
+
+	dialector, replicas := cfg.Dialector, cfg.Replicas
+	if cfg.StatementTimeout > 0 {
+		var err error
+		if dialector, err = withStatementTimeout(dialector, cfg.StatementTimeout); err != nil {
+			return nil, fmt.Errorf("set statement timeout failed, %w", err)
+		}
+		replicas = make([]gorm.Dialector, len(cfg.Replicas))
+		for i := range cfg.Replicas {
+			if replicas[i], err = withStatementTimeout(cfg.Replicas[i], cfg.StatementTimeout); err != nil {
+				return nil, fmt.Errorf("set replica statement timeout failed, %w", err)
+			}
+		}
+	}
+
+	opts = append(opts[:len(opts):len(opts)], configOption{cfg: cfg})
+	db, err := open(dialector, cfg, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("open database connection failed, %w", err)
 	}
@@ -38,27 +217,94 @@ func New(cfg *Config, opts ...gorm.Option) (*Gormx, error) {
 		return nil, fmt.Errorf("get origin db instance failed, %w", err)
 	}
 
-	if cfg.MaxIdleConn > 0 {
-		sqlDb.SetMaxIdleConns(cfg.MaxIdleConn)
+	// 连接已经建立，之后失败时需要关闭连接池
+	if err := initialize(db, sqlDb, cfg, replicas); err != nil {
+		_ = sqlDb.Close()
+		return nil, err
 	}
 
-	if cfg.MaxOpenConn > 0 {
-		sqlDb.SetMaxOpenConns(cfg.MaxOpenConn)
-	}
-
-	if cfg.MaxLifetime > 0 {
-		sqlDb.SetConnMaxLifetime(time.Duration(cfg.MaxLifetime) * time.Second)
+	if cfg.Context != nil {
+		db = db.WithContext(cfg.Context)
 	}
 
 	return &Gormx{
-		cfg: cfg,
-		db:  db,
+		cfg:      cfg,
+		db:       db,
+		registry: newScopeRegistry(),
 	}, nil
 }
 
+// initialize 注册回调、插件和读写分离，并设置连接池
+func initialize(db *gorm.DB, sqlDb *sql.DB, cfg *Config, replicas []gorm.Dialector) error {
+	if cfg.QueryTimeout > 0 {
+		if err := registerQueryTimeout(db, cfg.QueryTimeout); err != nil {
+			return fmt.Errorf("register query timeout callbacks failed, %w", err)
+		}
+	}
+
+	if cfg.TablePrefix != "" {
+		if err := registerTablePrefix(db, cfg.TablePrefix); err != nil {
+			return fmt.Errorf("register table prefix callbacks failed, %w", err)
+		}
+	}
+
+	for _, plugin := range cfg.Plugins {
+		if err := db.Use(plugin); err != nil {
+			return fmt.Errorf("register plugin %s failed, %w", plugin.Name(), err)
+		}
+	}
+
+	cfg.applyPool(sqlDb)
+
+	if len(replicas) > 0 {
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+		})
+		if err := db.Use(resolver); err != nil {
+			return fmt.Errorf("register db resolver failed, %w", err)
+		}
+		if err := resolver.Call(func(connPool gorm.ConnPool) error {
+			if sqlDb, ok := connPool.(*sql.DB); ok {
+				cfg.applyPool(sqlDb)
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("set replicas connection pool failed, %w", err)
+		}
+	}
+	return nil
+}
+
+// open 连接数据库，失败时按照 Config 中的配置重试，返回最后一次的错误
+func open(dialector gorm.Dialector, cfg *Config, opts ...gorm.Option) (*gorm.DB, error) {
+	interval := cfg.ConnectRetryInterval
+	if interval <= 0 {
+		interval = defaultConnectRetryInterval
+	}
+
+	for i := 0; ; i++ {
+		db, err := gorm.Open(dialector, opts...)
+		if err == nil {
+			return db, nil
+		}
+		if db != nil && db.ConnPool != nil {
+			if sqlDb, dbErr := db.DB(); dbErr == nil {
+				_ = sqlDb.Close()
+			}
+		}
+		if i >= cfg.ConnectRetries {
+			return nil, fmt.Errorf("after %d attempts, %w", i+1, err)
+		}
+
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
 func NewWithDB(db *gorm.DB) *Gormx {
 	return &Gormx{
-		db: db,
+		db:       db,
+		registry: newScopeRegistry(),
 	}
 }
 
@@ -66,10 +312,107 @@ func (s *Gormx) DB() *gorm.DB {
 	return s.db
 }
 
+// SQLDB 返回底层的 *sql.DB 连接池，用于需要原始连接的第三方库
+func (s *Gormx) SQLDB() (*sql.DB, error) {
+	return s.db.DB()
+}
+
+// Ping 检查数据库连接是否可用
+func (s *Gormx) Ping(ctx context.Context) error {
+	sqlDb, err := s.SQLDB()
+	if err != nil {
+		return err
+	}
+	return sqlDb.PingContext(ctx)
+}
+
+// Stats 连接池的统计信息
+func (s *Gormx) Stats() sql.DBStats {
+	sqlDb, err := s.SQLDB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDb.Stats()
+}
+
+// Close 关闭数据库连接池
+func (s *Gormx) Close() error {
+	sqlDb, err := s.SQLDB()
+	if err != nil {
+		return err
+	}
+	return sqlDb.Close()
+}
+
+// AutoMigrate 根据 model 自动迁移表结构，配置了 TablePrefix 时实现了 TableName 方法的模型同样会添加前缀
+func (s *Gormx) AutoMigrate(models ...interface{}) error {
+	prefix := s.tablePrefix()
+	if prefix == "" {
+		return s.db.AutoMigrate(models...)
+	}
+
+	others := make([]interface{}, 0, len(models))
+	for _, model := range models {
+		modelType := reflect.Indirect(reflect.ValueOf(model)).Type()
+		tabler, ok := model.(schema.Tabler)
+		if !ok || !isTabler(modelType) {
+			others = append(others, model)
+			continue
+		}
+		if err := s.db.Table(prefix + tabler.TableName()).AutoMigrate(model); err != nil {
+			return err
+		}
+	}
+	return s.db.AutoMigrate(others...)
+}
+
+// Migrator 返回 gorm 的 Migrator，用于更复杂的表结构操作
+func (s *Gormx) Migrator() gorm.Migrator {
+	return s.db.Migrator()
+}
+
 func (s *Gormx) BuildOptions(opts ...Option) *gorm.DB {
 	return s.buildWithOptions(opts...)
 }
 
+// DryRun 同 BuildOptions，但只生成 SQL 不执行，调用 Find、First 等方法后可以从返回值的 Statement 中获取 SQL 和参数
+func (s *Gormx) DryRun(opts ...Option) *gorm.DB {
+	return applyOptions(s.db.Session(&gorm.Session{DryRun: true}), s.withScopes(opts)...)
+}
+
+// Pagination 分页，每页数量的默认值和最大值使用 Config 中的配置
+func (s *Gormx) Pagination(page, size int) Option {
+	defaultSize, maxSize := s.cfg.pageSizes()
+	return pagination(page, size, defaultSize, maxSize)
+}
+
+// Keyset 游标分页，每页数量的默认值和最大值使用 Config 中的配置
+func (s *Gormx) Keyset(column string, lastValue interface{}, size int, desc bool) Option {
+	defaultSize, maxSize := s.cfg.pageSizes()
+	return keyset(column, lastValue, size, desc, defaultSize, maxSize)
+}
+
+// ToSQL 生成 fn 中查询的完整 SQL 但不执行，用于调试
+//
+//	sql := db.ToSQL(func(tx *Gormx) *gorm.DB {
+//		return tx.BuildOptions(WithId(1)).First(&User{})
+//	})
+func (s *Gormx) ToSQL(fn func(tx *Gormx) *gorm.DB) string {
+	return s.db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return fn(s.clone(tx))
+	})
+}
+
+// SubQuery 构建子查询，可以作为 WhereIn 的参数，作为 Where 的参数时需要加上括号，如 Where("id IN (?)", sub)
+// fn 中不能调用 Find、First 等会执行 SQL 的方法
+//
+//	db.FindMany(&users, WhereIn("id", db.SubQuery(func(tx *Gormx) *gorm.DB {
+//		return tx.BuildOptions(Where("age > ?", 18)).Model(&User{}).Select("id")
+//	})))
+func (s *Gormx) SubQuery(fn func(tx *Gormx) *gorm.DB) *gorm.DB {
+	return fn(s.clone(s.db.Session(&gorm.Session{NewDB: true})))
+}
+
 func (s *Gormx) Debug() *Gormx {
 	return s.clone(s.db.Debug())
 }
@@ -79,41 +422,307 @@ func (s *Gormx) WithContext(ctx context.Context) *Gormx {
 	return s.clone(s.db.WithContext(ctx))
 }
 
+// WithDefaultScopes 添加默认条件，会新建 Gormx 对象，之后的读写操作都会应用这些条件，Raw 和 Exec 除外
+func (s *Gormx) WithDefaultScopes(opts ...Option) *Gormx {
+	db := s.clone(s.db)
+	db.scopes = s.withScopes(opts)
+	return db
+}
+
+// WithoutDefaultScopes 去掉 WithDefaultScopes 添加的默认条件，会新建 Gormx 对象
+func (s *Gormx) WithoutDefaultScopes() *Gormx {
+	db := s.clone(s.db)
+	db.scopes = nil
+	return db
+}
+
 func (s *Gormx) Model(value interface{}) *Gormx {
 	return s.clone(s.db.Model(value))
 }
 
+// WithConn 使用 conn 执行之后的操作，保留当前的配置
 func (s *Gormx) WithConn(conn *gorm.DB) *Gormx {
 	return s.clone(conn)
 }
 
-// Tx 开启事务
+// Transactional 在外部已经开启的 gorm 事务中执行操作，提交和回滚由调用方负责
+//
+//	tx := gormDb.Begin()
+//	defer tx.Rollback()
+//	if err := db.Transactional(tx).Insert(&user); err != nil {
+//		return err
+//	}
+//	return tx.Commit().Error
+func (s *Gormx) Transactional(tx *gorm.DB) *Gormx {
+	return s.WithConn(tx)
+}
+
+// Tx 开启事务，在回调中再次调用 tx.Tx 时会使用同一个连接，通过 SAVEPOINT 实现嵌套事务
+// 事务及回调中的所有语句都使用 WithContext 设置的上下文，上下文取消后事务会失败并回滚
 func (s *Gormx) Tx(fn func(tx *Gormx) error, opts ...*sql.TxOptions) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		return fn(s.WithConn(tx))
 	}, opts...)
 }
 
+// TxSafe 同 Tx，回调发生 panic 时回滚事务并返回包含 ErrTxPanic 的错误，不会继续抛出 panic
+func (s *Gormx) TxSafe(fn func(tx *Gormx) error, opts ...*sql.TxOptions) error {
+	return s.Tx(func(tx *Gormx) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w, %v", ErrTxPanic, r)
+			}
+		}()
+		return fn(tx)
+	}, opts...)
+}
+
+// TxWithRetry 开启事务，遇到死锁或序列化失败时最多重试 maxRetries 次，每次重试的间隔时间翻倍
+func (s *Gormx) TxWithRetry(fn func(tx *Gormx) error, maxRetries int, opts ...*sql.TxOptions) error {
+	backoff := defaultTxBackoff
+	for i := 0; ; i++ {
+		err := s.Tx(fn, opts...)
+		if err == nil || i >= maxRetries || !isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-s.context().Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
 func (s *Gormx) Insert(doc interface{}, opts ...Option) error {
 	return s.buildWithOptions(opts...).Create(doc).Error
 }
 
+// InsertN 同 Insert，同时返回插入的行数
+func (s *Gormx) InsertN(doc interface{}, opts ...Option) (int64, error) {
+	return rowsAffected(s.buildWithOptions(opts...).Create(doc))
+}
+
+// InsertIgnore 插入记录，与 conflictColumns 冲突时忽略，inserted 表示是否插入了新记录
+func (s *Gormx) InsertIgnore(doc interface{}, conflictColumns ...string) (bool, error) {
+	n, err := s.InsertN(doc, NoConflict(conflictColumns...))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// InsertInBatches 分批插入，batchSize 小于等于 0 时使用默认值
+func (s *Gormx) InsertInBatches(docs interface{}, batchSize int, opts ...Option) error {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return s.buildWithOptions(opts...).CreateInBatches(docs, batchSize).Error
+}
+
+// Upsert 插入记录，与 conflictColumns 冲突时更新 updateColumns，updateColumns 为空时更新所有字段
+func (s *Gormx) Upsert(doc interface{}, conflictColumns []string, updateColumns []string, opts ...Option) error {
+	opts = append(opts[:len(opts):len(opts)], OnConflictUpdate(conflictColumns, updateColumns))
+	return s.buildWithOptions(opts...).Create(doc).Error
+}
+
+// UpsertReturning 同 Upsert，完成后将数据库中的最新数据写回 doc，doc 只能是单个结构体的指针
+// 支持 RETURNING 的数据库直接返回，其他数据库根据 conflictColumns 的值重新查询，conflictColumns 为空时使用主键
+func (s *Gormx) UpsertReturning(doc interface{}, conflictColumns []string, updateColumns []string) error {
+	if supportsReturning(s.db) {
+		return s.Upsert(doc, conflictColumns, updateColumns, Returning())
+	}
+
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(doc); err != nil {
+		return err
+	}
+	fields := stmt.Schema.PrimaryFields
+	if len(conflictColumns) > 0 {
+		fields = make([]*schema.Field, len(conflictColumns))
+		for i, column := range conflictColumns {
+			if fields[i] = stmt.Schema.LookUpField(column); fields[i] == nil {
+				return fmt.Errorf("conflict column %s not found in %s", column, stmt.Schema.Name)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("no conflict columns or primary key in %s", stmt.Schema.Name)
+	}
+
+	if err := s.Upsert(doc, conflictColumns, updateColumns); err != nil {
+		return err
+	}
+
+	// 插入后才能取到自增主键等数据库生成的值
+	rv := reflect.Indirect(reflect.ValueOf(doc))
+	conds := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, zero := field.ValueOf(s.context(), rv)
+		// 条件为空值时会查询到其他记录，例如 mysql 更新已有记录时不会返回自增主键
+		if zero {
+			return fmt.Errorf("reload %s failed, %s is empty after upsert", stmt.Schema.Name, field.Name)
+		}
+		conds[field.DBName] = value
+	}
+	stored := reflect.New(rv.Type())
+	if err := s.buildWithOptions().Where(conds).Take(stored.Interface()).Error; err != nil {
+		return err
+	}
+	rv.Set(stored.Elem())
+	return nil
+}
+
+// UpsertWhere 同 Upsert，只有满足 where 条件时才更新，如 "test_users.version < excluded.version"，mysql 不支持
+func (s *Gormx) UpsertWhere(doc interface{}, conflictColumns []string, updateColumns []string, where string, args ...interface{}) error {
+	return s.buildWithOptions(OnConflictUpdateWhere(conflictColumns, updateColumns, where, args...)).Create(doc).Error
+}
+
+// UpsertMany 分批插入多条记录，冲突处理同 Upsert，batchSize 小于等于 0 时使用默认值
+func (s *Gormx) UpsertMany(docs interface{}, conflictColumns []string, updateColumns []string, batchSize int, opts ...Option) error {
+	opts = append(opts[:len(opts):len(opts)], OnConflictUpdate(conflictColumns, updateColumns))
+	return s.InsertInBatches(docs, batchSize, opts...)
+}
+
+// Save 保存所有字段，主键为空或者记录不存在时会插入新记录，所以不会返回 ErrNoRowsAffected
 func (s *Gormx) Save(doc interface{}, opts ...Option) error {
 	return s.buildWithOptions(opts...).Save(doc).Error
 }
 
+// SaveN 同 Save，同时返回影响的行数
+func (s *Gormx) SaveN(doc interface{}, opts ...Option) (int64, error) {
+	return rowsAffected(s.buildWithOptions(opts...).Save(doc))
+}
+
+// FindOne 查询按主键排序的第一条记录，记录不存在时返回的错误包含模型和 SQL，可以通过 IsNotFound 判断
 func (s *Gormx) FindOne(dest interface{}, opts ...Option) error {
-	return s.buildWithOptions(opts...).First(dest).Error
+	return wrapNotFound(s.buildWithOptions(opts...).First(dest), dest)
+}
+
+// FindLast 查询按主键倒序的第一条记录，即主键最大的记录
+func (s *Gormx) FindLast(dest interface{}, opts ...Option) error {
+	return wrapNotFound(s.buildWithOptions(opts...).Last(dest), dest)
+}
+
+// Take 查询单条记录，与 FindOne 不同的是不会按主键排序，适用于根据唯一键查询
+func (s *Gormx) Take(dest interface{}, opts ...Option) error {
+	return wrapNotFound(s.buildWithOptions(opts...).Take(dest), dest)
+}
+
+// FindOneOptional 查询单条记录，记录不存在时返回 false 且不返回错误
+func (s *Gormx) FindOneOptional(dest interface{}, opts ...Option) (bool, error) {
+	if err := s.FindOne(dest, opts...); err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 func (s *Gormx) FindMany(dest interface{}, opts ...Option) error {
 	return s.buildWithOptions(opts...).Find(dest).Error
 }
 
+// FindPage 分页查询，同时返回符合条件的记录总数
+func (s *Gormx) FindPage(dest interface{}, page, size int, opts ...Option) (int64, error) {
+	var total int64
+	opts = s.withScopes(opts)
+	countDb := applyOptions(s.session(), append(opts[:len(opts):len(opts)], countOnly)...)
+	if countDb.Statement.Model == nil {
+		countDb = countDb.Model(dest)
+	}
+	if err := countDb.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	opts = append(opts[:len(opts):len(opts)], s.Pagination(page, size))
+	if err := applyOptions(s.session(), opts...).Find(dest).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// countOnly 统计总数时使用 count(*) 代替 Select 的字段，只保留条件，Distinct 单个字段时统计去重后的数量
+// 需要放在最后应用，Select 会清除 Count 设置的 count(*)
+func countOnly(db *gorm.DB) *gorm.DB {
+	expr := clause.Expr{SQL: "count(*)"}
+	if db.Statement.Distinct {
+		if len(db.Statement.Selects) != 1 {
+			return db
+		}
+		expr = clause.Expr{SQL: "COUNT(DISTINCT(?))", Vars: []interface{}{clause.Column{Name: db.Statement.Selects[0]}}}
+		db.Statement.Distinct = false
+	}
+	db.Statement.Selects = nil
+	db.Statement.AddClause(clause.Select{Expression: expr})
+	return db
+}
+
+// FirstOrCreate 查询第一条符合条件的记录，不存在时使用条件创建，created 表示是否新建了记录
+func (s *Gormx) FirstOrCreate(dest interface{}, opts ...Option) (bool, error) {
+	db := s.buildWithOptions(opts...).FirstOrCreate(dest)
+	if err := db.Error; err != nil {
+		return false, err
+	}
+	return db.RowsAffected > 0, nil
+}
+
+// FirstOrInit 查询第一条符合条件的记录，不存在时使用条件初始化 dest 但不写入数据库，found 表示是否查询到了记录
+// 只有 map、结构体或者 Tenant 等等值条件会用于初始化，字符串条件会被忽略
+func (s *Gormx) FirstOrInit(dest interface{}, opts ...Option) (bool, error) {
+	db := s.buildWithOptions(opts...).FirstOrInit(dest)
+	if err := db.Error; err != nil {
+		return false, err
+	}
+	return db.RowsAffected > 0, nil
+}
+
+// FindInBatches 分批查询，每批数据查询后调用 fn，fn 返回错误时终止查询
+func (s *Gormx) FindInBatches(dest interface{}, batchSize int, fn func(tx *Gormx, batch int) error, opts ...Option) error {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return s.buildWithOptions(opts...).FindInBatches(dest, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(s.clone(tx), batch)
+	}).Error
+}
+
+// Iterate 逐行读取查询结果，适用于导出大表等无法一次性加载的场景，需要通过 Model 指定表
+// 每行数据调用一次 fn，在 fn 中调用 scan 将当前行映射到 dest，fn 返回错误时终止读取
+func (s *Gormx) Iterate(fn func(scan func(dest interface{}) error) error, opts ...Option) error {
+	db, cancel := s.rowsTimeout(s.buildWithOptions(opts...))
+	defer cancel()
+	rows, err := db.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scan := func(dest interface{}) error {
+		return db.ScanRows(rows, dest)
+	}
+	for rows.Next() {
+		if err := fn(scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Pluck 查询单个字段，与 Pagination 一起使用时需要通过 OrderBy 指定排序，否则分页的结果不稳定
 func (s *Gormx) Pluck(column string, dest interface{}, opts ...Option) error {
 	return s.buildWithOptions(opts...).Pluck(column, dest).Error
 }
 
+// PluckMany 查询多个字段，dest 可以是结构体或者 map 的切片，需要通过 Model 指定表
+func (s *Gormx) PluckMany(columns []string, dest interface{}, opts ...Option) error {
+	return s.scan(s.buildWithOptions(opts...).Select(columns), dest)
+}
+
 func (s *Gormx) Count(opts ...Option) (int64, error) {
 	var total int64
 	if err := s.buildWithOptions(opts...).Count(&total).Error; err != nil {
@@ -122,67 +731,291 @@ func (s *Gormx) Count(opts ...Option) (int64, error) {
 	return total, nil
 }
 
+// CountWithDeleted 统计记录数，包含软删除的记录
+func (s *Gormx) CountWithDeleted(opts ...Option) (int64, error) {
+	opts = append(opts[:len(opts):len(opts)], Unscoped())
+	return s.Count(opts...)
+}
+
+// CountDistinct 统计字段去重后的数量
+func (s *Gormx) CountDistinct(column string, opts ...Option) (int64, error) {
+	var total int64
+	if err := s.buildWithOptions(opts...).Distinct(column).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Sum 求和，没有符合条件的记录时返回 0
+func (s *Gormx) Sum(column string, opts ...Option) (float64, error) {
+	return s.aggregate("SUM", column, opts...)
+}
+
+// Avg 求平均值，没有符合条件的记录时返回 0
+func (s *Gormx) Avg(column string, opts ...Option) (float64, error) {
+	return s.aggregate("AVG", column, opts...)
+}
+
+// Max 求最大值，没有符合条件的记录时返回 0
+func (s *Gormx) Max(column string, opts ...Option) (float64, error) {
+	return s.aggregate("MAX", column, opts...)
+}
+
+// Min 求最小值，没有符合条件的记录时返回 0
+func (s *Gormx) Min(column string, opts ...Option) (float64, error) {
+	return s.aggregate("MIN", column, opts...)
+}
+
+// Exists 检查记录是否存在，表名由 dest 决定，dest 中只有非零的主键会作为查询条件，其他字段需要通过 opts 传入
 func (s *Gormx) Exists(dest interface{}, opts ...Option) (bool, error) {
 	var exists bool
 	opts = append(opts, Wildcard())
-	stmt := s.dryRun(opts...).Take(dest).Statement
-	query := s.db.Raw(fmt.Sprintf("SELECT EXISTS(%s)", stmt.SQL.String()), stmt.Vars...)
-	if err := query.Scan(&exists).Error; err != nil {
+	stmt := s.DryRun(opts...).Model(dest).Take(dest).Statement
+	query := s.session().Raw(fmt.Sprintf("SELECT EXISTS(%s)", stmt.SQL.String()), stmt.Vars...)
+	if err := s.scan(query, &exists); err != nil {
 		return false, err
 	}
 	return exists, nil
 }
 
 func (s *Gormx) Updates(dest interface{}, opts ...Option) error {
-	db := s.buildWithOptions(opts...).Updates(dest)
-	if err := db.Error; err != nil {
+	return checkRowsAffected(s.buildWithOptions(opts...).Updates(dest))
+}
+
+// UpdatesN 同 Updates，返回更新的行数，没有更新任何记录时不会返回 ErrNoRowsAffected
+func (s *Gormx) UpdatesN(dest interface{}, opts ...Option) (int64, error) {
+	return rowsAffected(s.buildWithOptions(opts...).Updates(dest))
+}
+
+// UpdateColumns 更新多个字段，与 Updates 不同的是不会执行钩子函数，也不会自动更新 UpdatedAt 字段
+func (s *Gormx) UpdateColumns(values interface{}, opts ...Option) error {
+	return checkRowsAffected(s.buildWithOptions(opts...).UpdateColumns(values))
+}
+
+// UpdateBatch 根据主键批量更新多条记录的 columns 字段，每条记录的值可以不同，docs 为结构体的切片
+// 通过一条 UPDATE ... SET column = CASE WHEN ... END WHERE id IN (...) 语句完成更新
+func (s *Gormx) UpdateBatch(docs interface{}, columns []string) error {
+	rv := reflect.Indirect(reflect.ValueOf(docs))
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("docs should be a slice, got %s", rv.Kind())
+	}
+	if rv.Len() == 0 || len(columns) == 0 {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(docs); err != nil {
 		return err
 	}
-	if db.RowsAffected == 0 {
-		return ErrNoRowsAffected
+	if len(stmt.Schema.PrimaryFields) == 0 {
+		return fmt.Errorf("primary key not found in %s", stmt.Schema.Name)
 	}
-	return nil
+
+	ctx := s.context()
+	conds := make([]clause.Expression, rv.Len())
+	for i := range conds {
+		elem := reflect.Indirect(rv.Index(i))
+		exprs := make([]clause.Expression, 0, len(stmt.Schema.PrimaryFields))
+		for _, field := range stmt.Schema.PrimaryFields {
+			value, isZero := field.ValueOf(ctx, elem)
+			if isZero {
+				return fmt.Errorf("primary key %s of %s should not be zero", field.DBName, stmt.Schema.Name)
+			}
+			exprs = append(exprs, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+		}
+		conds[i] = clause.And(exprs...)
+	}
+
+	values := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		field := stmt.Schema.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("column %s not found in %s", column, stmt.Schema.Name)
+		}
+		var (
+			sql  strings.Builder
+			vars = make([]interface{}, 0, len(conds)*2+1)
+		)
+		sql.WriteString("CASE")
+		for i := range conds {
+			value, _ := field.ValueOf(ctx, reflect.Indirect(rv.Index(i)))
+			sql.WriteString(" WHEN ? THEN ?")
+			vars = append(vars, conds[i], value)
+		}
+		sql.WriteString(" ELSE ? END")
+		vars = append(vars, clause.Column{Name: field.DBName})
+		values[field.DBName] = clause.Expr{SQL: sql.String(), Vars: vars}
+	}
+	// Model 为切片时 gorm 会根据主键添加 WHERE id IN (...) 条件
+	return checkRowsAffected(s.buildWithOptions().Model(docs).Updates(values))
 }
 
 func (s *Gormx) Update(column string, value interface{}, opts ...Option) error {
-	db := s.buildWithOptions(opts...).Update(column, value)
-	if err := db.Error; err != nil {
+	return checkRowsAffected(s.buildWithOptions(opts...).Update(column, value))
+}
+
+// UpdateN 同 Update，返回更新的行数，没有更新任何记录时不会返回 ErrNoRowsAffected
+func (s *Gormx) UpdateN(column string, value interface{}, opts ...Option) (int64, error) {
+	return rowsAffected(s.buildWithOptions(opts...).Update(column, value))
+}
+
+// Increment 字段自增 delta，没有更新任何记录时返回 ErrNoRowsAffected
+func (s *Gormx) Increment(column string, delta interface{}, opts ...Option) error {
+	return s.Update(column, gorm.Expr("? + ?", clause.Column{Name: column}, delta), opts...)
+}
+
+// Decrement 字段自减 delta，没有更新任何记录时返回 ErrNoRowsAffected
+func (s *Gormx) Decrement(column string, delta interface{}, opts ...Option) error {
+	return s.Update(column, gorm.Expr("? - ?", clause.Column{Name: column}, delta), opts...)
+}
+
+// Delete 删除记录，没有删除任何记录时返回 ErrNoRowsAffected
+func (s *Gormx) Delete(dest interface{}, opts ...Option) error {
+	return checkRowsAffected(s.buildWithOptions(opts...).Delete(dest))
+}
+
+// DeleteN 同 Delete，返回删除的行数，没有删除任何记录时不会返回 ErrNoRowsAffected
+func (s *Gormx) DeleteN(dest interface{}, opts ...Option) (int64, error) {
+	return rowsAffected(s.buildWithOptions(opts...).Delete(dest))
+}
+
+// DeleteWhere 删除所有符合条件的记录，返回删除的行数，model 只用于确定表名
+// 没有传入任何条件时 gorm 会返回 gorm.ErrMissingWhereClause，避免误删全表
+func (s *Gormx) DeleteWhere(model interface{}, opts ...Option) (int64, error) {
+	return s.DeleteN(model, opts...)
+}
+
+// Restore 恢复软删除的记录，model 需要包含 gorm.DeletedAt 字段，没有恢复任何记录时返回 ErrNoRowsAffected
+func (s *Gormx) Restore(model interface{}, opts ...Option) error {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(model); err != nil {
 		return err
 	}
-	if db.RowsAffected == 0 {
-		return ErrNoRowsAffected
+	var column string
+	for _, field := range stmt.Schema.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) && field.DBName != "" {
+			column = field.DBName
+			break
+		}
+	}
+	if column == "" {
+		return fmt.Errorf("%s has no gorm.DeletedAt field", stmt.Schema.Name)
 	}
-	return nil
-}
 
-func (s *Gormx) Delete(dest interface{}, opts ...Option) error {
-	return s.buildWithOptions(opts...).Delete(dest).Error
+	opts = append(opts[:len(opts):len(opts)], Unscoped(), IsNotNull(column))
+	return checkRowsAffected(s.buildWithOptions(opts...).Model(model).Update(column, nil))
 }
 
+// Raw 执行原生 SQL，支持 ? 占位符，也支持通过 sql.Named 或 map[string]interface{} 传入 @name 形式的命名参数
 func (s *Gormx) Raw(sql string, values ...interface{}) *Gormx {
 	return s.clone(s.db.Raw(sql, values...))
 }
 
+// RawCount 统计原生查询 SQL 返回的记录数，SQL 会作为子查询执行 SELECT COUNT(*) FROM (sql) t
+func (s *Gormx) RawCount(sql string, values ...interface{}) (int64, error) {
+	var total int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM (%s) t", strings.TrimSuffix(strings.TrimSpace(sql), ";"))
+	if err := s.scan(s.session().Raw(query, values...), &total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
 func (s *Gormx) Exec(sql string, values ...interface{}) error {
 	return s.db.Exec(sql, values...).Error
 }
 
+// Scan 将查询结果映射到 dest，dest 可以是任意结构体，也可以是 map[string]interface{} 或 []map[string]interface{}
 func (s *Gormx) Scan(dest interface{}) error {
-	return s.db.Scan(dest).Error
+	return s.scan(s.db, dest)
 }
 
 // ----------------------------------------------------------------------------------------------------------------------------
 
-func (s *Gormx) dryRun(opts ...Option) *gorm.DB {
-	return applyOptions(s.db.Session(&gorm.Session{DryRun: true}), opts...)
+func (s *Gormx) aggregate(fn string, column string, opts ...Option) (float64, error) {
+	var result sql.NullFloat64
+	db := s.buildWithOptions(opts...).Select(fn+"(?)", clause.Column{Name: column})
+	if err := s.scan(db, &result); err != nil {
+		return 0, err
+	}
+	return result.Float64, nil
+}
+
+func (s *Gormx) context() context.Context {
+	if ctx := s.db.Statement.Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// supportsReturning 数据库是否支持 INSERT ... RETURNING
+func supportsReturning(db *gorm.DB) bool {
+	for _, name := range db.Callback().Create().Clauses {
+		if name == "RETURNING" {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapNotFound 记录不存在时在错误中加上模型和查询条件，便于定位问题，errors.Is(err, ErrNotFound) 仍然成立
+func wrapNotFound(db *gorm.DB, dest interface{}) error {
+	err := db.Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	typ := reflect.TypeOf(dest)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	// 执行完成后 Statement 中的 SQL 已经被清空，需要重新构建 WHERE 子句
+	stmt := &gorm.Statement{DB: db, Table: db.Statement.Table, Clauses: db.Statement.Clauses}
+	stmt.Build("WHERE")
+	if stmt.SQL.Len() == 0 {
+		return fmt.Errorf("%w, model: %s", err, typ)
+	}
+	return fmt.Errorf("%w, model: %s, %s", err, typ, db.Dialector.Explain(stmt.SQL.String(), stmt.Vars...))
+}
+
+func checkRowsAffected(db *gorm.DB) error {
+	if err := db.Error; err != nil {
+		return err
+	}
+	if db.RowsAffected == 0 {
+		return ErrNoRowsAffected
+	}
+	return nil
+}
+
+func rowsAffected(db *gorm.DB) (int64, error) {
+	if err := db.Error; err != nil {
+		return 0, err
+	}
+	return db.RowsAffected, nil
+}
+
+// session 新建会话，保证多次查询之间的条件互不影响
+func (s *Gormx) session() *gorm.DB {
+	return s.db.Session(&gorm.Session{})
 }
 
 func (s *Gormx) buildWithOptions(opts ...Option) *gorm.DB {
-	return applyOptions(s.db, opts...)
+	return applyOptions(s.db, s.withScopes(opts)...)
+}
+
+// withScopes 将默认条件放在 opts 之前
+func (s *Gormx) withScopes(opts []Option) []Option {
+	if len(s.scopes) == 0 {
+		return opts
+	}
+	return append(s.scopes[:len(s.scopes):len(s.scopes)], opts...)
 }
 
 func (s *Gormx) clone(db *gorm.DB) *Gormx {
 	return &Gormx{
-		db: db,
+		cfg:      s.cfg,
+		db:       db,
+		scopes:   s.scopes,
+		registry: s.registry,
 	}
 }
@@ -2,11 +2,21 @@ package gormx
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
 )
 
 func TestGormxTestSuite(t *testing.T) {
@@ -23,32 +33,67 @@ func (User) TableName() string {
 	return "test_users"
 }
 
+type Order struct {
+	Id     int64
+	UserId int64
+	Items  []OrderItem
+}
+
+func (Order) TableName() string {
+	return "test_orders"
+}
+
+type OrderItem struct {
+	Id      int64
+	OrderId int64
+	Name    string
+}
+
+func (OrderItem) TableName() string {
+	return "test_order_items"
+}
+
+type SoftUser struct {
+	Id        int64
+	Nickname  string
+	DeletedAt gorm.DeletedAt
+}
+
+func (SoftUser) TableName() string {
+	return "test_soft_users"
+}
+
 type GormxTestSuite struct {
 	suite.Suite
 
 	db *Gormx
 }
 
+var memorySeq int64
+
 func (suite *GormxTestSuite) SetupTest() {
+	// 与 sqlitex.NewInMemory 相同，每个测试使用独立的内存数据库，相同 Dialector 创建的连接访问同一个数据库
+	// sqlitex 依赖 gormx，这里不能直接引用
 	conf := &Config{
-		Dialector:   nil, //fill driver
+		Dialector:   sqlite.Open(fmt.Sprintf("file:gormx_test_%d?mode=memory&cache=shared", atomic.AddInt64(&memorySeq, 1))),
 		MaxIdleConn: 10,
 		MaxOpenConn: 10,
 		MaxLifetime: 1000,
 		Debug:       false,
 	}
 	db, err := New(conf)
-	suite.Assert().Nil(err)
+	suite.Require().Nil(err)
 	suite.db = db
 	suite.initUsers()
 }
 
 func (suite *GormxTestSuite) TearDownTest() {
 	suite.db.Exec("drop table test_users;")
+	suite.db.Close()
 }
 
 func (suite *GormxTestSuite) initUsers() {
-	suite.db.Exec("create table test_users (id serial primary key not null, nickname varchar(64) not null, age integer default 0);")
+	suite.db.Exec("create table test_users (id integer primary key autoincrement not null, nickname varchar(64) not null, age integer default 0);")
 	var users []User
 	for i := 0; i < 2; i++ {
 		users = append(users, User{
@@ -60,6 +105,34 @@ func (suite *GormxTestSuite) initUsers() {
 	suite.Assert().Nil(suite.db.Insert(users))
 }
 
+func (suite *GormxTestSuite) initOrders() {
+	suite.db.Exec("create table test_orders (id integer primary key autoincrement not null, user_id integer not null);")
+	suite.db.Exec("create table test_order_items (id integer primary key autoincrement not null, order_id integer not null, name varchar(64) not null);")
+	orders := []Order{
+		{UserId: 1, Items: []OrderItem{{Name: "apple"}, {Name: "banana"}}},
+		{UserId: 2, Items: []OrderItem{{Name: "cherry"}}},
+	}
+	suite.Assert().Nil(suite.db.Insert(orders))
+}
+
+func (suite *GormxTestSuite) dropOrders() {
+	suite.db.Exec("drop table test_order_items;")
+	suite.db.Exec("drop table test_orders;")
+}
+
+func (suite *GormxTestSuite) initSoftUsers() {
+	suite.db.Exec("create table test_soft_users (id integer primary key autoincrement not null, nickname varchar(64) not null, deleted_at timestamp null);")
+	users := []SoftUser{
+		{Nickname: "hello 0"},
+		{Nickname: "hello 1"},
+	}
+	suite.Assert().Nil(suite.db.Insert(users))
+}
+
+func (suite *GormxTestSuite) dropSoftUsers() {
+	suite.db.Exec("drop table test_soft_users;")
+}
+
 func (suite *GormxTestSuite) TestFindOne() {
 	var user = User{
 		Id: 1,
@@ -70,6 +143,35 @@ func (suite *GormxTestSuite) TestFindOne() {
 	}
 }
 
+func (suite *GormxTestSuite) TestFindLast() {
+	var user User
+	err := suite.db.FindLast(&user)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, user.Id)
+		suite.Equal("hello 1", user.Nickname)
+	}
+
+	user = User{}
+	err = suite.db.FindLast(&user, Where("age < ?", 1))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, user.Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestTake() {
+	var user User
+	err := suite.db.Take(&user, WithId(2))
+	if suite.Assert().Nil(err) {
+		suite.Equal("hello 1", user.Nickname)
+	}
+
+	sql := suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(WithId(2)).Take(&User{})
+	})
+	suite.Contains(sql, "WHERE id=2")
+	suite.NotContains(sql, "ORDER BY")
+}
+
 func (suite *GormxTestSuite) TestExists() {
 	var (
 		exists bool
@@ -120,6 +222,48 @@ func (suite *GormxTestSuite) TestPluck() {
 	suite.Equal([]int64{1, 2}, ids)
 }
 
+func (suite *GormxTestSuite) TestLimitOffset() {
+	var users []User
+	err := suite.db.FindMany(&users, Limit(1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(1, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, OrderBy("id", false), Limit(1), Offset(1))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Limit(-1), Offset(-1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	// 负数会取消之前设置的分页
+	users = nil
+	err = suite.db.FindMany(&users, Pagination(2, 1), Limit(-1), Offset(-1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestPluckPagination() {
+	suite.Assert().Nil(suite.db.Insert(&User{Nickname: "hello 2", Age: 2}))
+
+	var pages [][]int64
+	for page := 1; page <= 2; page++ {
+		var ids []int64
+		err := suite.db.Model(&User{}).Pluck("id", &ids, OrderBy("id", true), Pagination(page, 2))
+		if !suite.Assert().Nil(err) {
+			return
+		}
+		pages = append(pages, ids)
+	}
+	suite.Equal([][]int64{{3, 2}, {1}}, pages)
+}
+
 func (suite *GormxTestSuite) TestCount() {
 	total, err := suite.db.Model(&User{}).Count()
 	if !suite.Assert().Nil(err) {
@@ -188,8 +332,11 @@ func (suite *GormxTestSuite) TestUpdates() {
 }
 
 func (suite *GormxTestSuite) TestDelete() {
+	err := suite.db.Delete(&User{Id: -1})
+	suite.ErrorIs(err, ErrNoRowsAffected)
+
 	user := User{Id: 1}
-	err := suite.db.Delete(&user)
+	err = suite.db.Delete(&user)
 	if suite.Nil(err) {
 		err = suite.db.FindOne(&user)
 		suite.ErrorIs(err, gorm.ErrRecordNotFound)
@@ -252,3 +399,2327 @@ func (suite *GormxTestSuite) TestTx() {
 		}
 	}
 }
+
+func (suite *GormxTestSuite) TestOrderBy() {
+	var users []User
+	err := suite.db.FindMany(&users, OrderBy("age", true), Pagination(1, 10))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(users)) {
+		suite.EqualValues(1, users[0].Age)
+		suite.EqualValues(0, users[1].Age)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, OrderByFields(OrderField{Column: "age"}, OrderField{Column: "id", Desc: true}))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(users)) {
+		suite.EqualValues(0, users[0].Age)
+		suite.EqualValues(1, users[1].Age)
+	}
+}
+
+func (suite *GormxTestSuite) TestOrderByLower() {
+	err := suite.db.Insert([]User{
+		{Nickname: "Bob"},
+		{Nickname: "alice"},
+		{Nickname: "Carol"},
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	var nicknames []string
+	err = suite.db.Model(&User{}).Pluck("nickname", &nicknames, Where("id > ?", 2), OrderByLower("nickname", false))
+	if suite.Assert().Nil(err) {
+		suite.Equal([]string{"alice", "Bob", "Carol"}, nicknames)
+	}
+
+	nicknames = nil
+	err = suite.db.Model(&User{}).Pluck("nickname", &nicknames, Where("id > ?", 2), OrderByLower("nickname", true))
+	if suite.Assert().Nil(err) {
+		suite.Equal([]string{"Carol", "Bob", "alice"}, nicknames)
+	}
+}
+
+func (suite *GormxTestSuite) TestRegisterScope() {
+	suite.db.RegisterScope("adults", Where("age >= ?", 1))
+
+	var users []User
+	err := suite.db.FindMany(&users, suite.db.UseScope("adults"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+
+	// 克隆出来的对象共享注册的条件
+	total, err := suite.db.Model(&User{}).Count(suite.db.WithContext(context.Background()).UseScope("adults"))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, total)
+	}
+
+	users = nil
+	suite.NotNil(suite.db.FindMany(&users, suite.db.UseScope("unknown")))
+}
+
+func (suite *GormxTestSuite) TestIf() {
+	var users []User
+	err := suite.db.FindMany(&users, If(false, Where("age > ?", 0)))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, If(true, Where("age > ?", 0)))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+}
+
+func (suite *GormxTestSuite) TestCompose() {
+	adults := Compose(Where("age >= ?", 1), Where("nickname LIKE ?", "hello%"))
+
+	var composed, separate []User
+	err := suite.db.FindMany(&composed, adults, OrderBy("id", false))
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	err = suite.db.FindMany(&separate, Where("age >= ?", 1), Where("nickname LIKE ?", "hello%"), OrderBy("id", false))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(composed)) {
+		suite.Equal(separate, composed)
+	}
+}
+
+func (suite *GormxTestSuite) TestWhereGroup() {
+	sql := suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(WhereGroup(Where("age = ?", 0), Where("age = ?", 1))).Find(&[]User{})
+	})
+	suite.Contains(sql, "WHERE (age = 0 OR age = 1)")
+
+	sql = suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(
+			Where("nickname LIKE ?", "hello%"),
+			WhereGroup(Compose(Where("age = ?", 0), Where("id = ?", 1)), Where("age = ?", 1)),
+		).Find(&[]User{})
+	})
+	suite.Contains(sql, " AND ((age = 0 AND id = 1) OR age = 1)")
+
+	var users []User
+	err := suite.db.FindMany(&users, WhereGroup(Where("age = ?", 0), Where("age = ?", 1)))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Where("age = ?", 0), Or("age = ?", 1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	// 条件出错时返回错误，不能查询到所有记录
+	users = nil
+	err = suite.db.FindMany(&users, WhereGroup(Where("age = ?", 0), suite.db.UseScope("typo")))
+	suite.NotNil(err)
+	suite.Equal(0, len(users))
+}
+
+func (suite *GormxTestSuite) TestSubQuery() {
+	var users []User
+	err := suite.db.FindMany(&users, WhereIn("id", suite.db.SubQuery(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(Where("age > ?", 0)).Model(&User{}).Select("id")
+	})))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+}
+
+func (suite *GormxTestSuite) TestWhere() {
+	var users []User
+	err := suite.db.FindMany(&users, Where("age > ?", 0))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Where(map[string]interface{}{"nickname": "hello 0"}))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(0, users[0].Age)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Where("age >= ?", 0), Where("nickname = ?", "hello 1"), Pagination(1, 10))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(1, users[0].Age)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Where("age >= ?", 0), Pagination(1, 1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(1, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestWhereIn() {
+	var users []User
+	err := suite.db.FindMany(&users, WhereIn("id", []int64{1, 2}))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, WhereIn("id", []int64{}))
+	if suite.Assert().Nil(err) {
+		suite.Equal(0, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestFindPage() {
+	var users []User
+	total, err := suite.db.FindPage(&users, 1, 1)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+		suite.Equal(1, len(users))
+	}
+
+	users = nil
+	total, err = suite.db.FindPage(&users, 1, 10, Where("age > ?", 0))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, total)
+		suite.Equal(1, len(users))
+	}
+
+	// 只查询部分字段时总数仍然使用 count(*)
+	users = nil
+	total, err = suite.db.FindPage(&users, 1, 10, Select("id", "nickname"), Where("age >= ?", 0))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(users)) {
+		suite.EqualValues(2, total)
+		suite.Equal("hello 0", users[0].Nickname)
+		suite.EqualValues(0, users[1].Age)
+	}
+
+	if !suite.Assert().Nil(suite.db.Insert(&User{Nickname: "hello 0", Age: 2})) {
+		return
+	}
+	var nicknames []User
+	total, err = suite.db.FindPage(&nicknames, 1, 10, Distinct("nickname"), OrderBy("nickname", false))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(nicknames)) {
+		suite.EqualValues(2, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestFindT() {
+	user, err := FindOneT[User](suite.db, WithId(1))
+	if suite.Assert().Nil(err) {
+		suite.Equal("hello 0", user.Nickname)
+	}
+
+	user, err = FindOneT[User](suite.db, WithId(-1))
+	suite.True(IsNotFound(err))
+	suite.Nil(user)
+
+	users, err := FindManyT[User](suite.db, Where("age > ?", 0))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+}
+
+func (suite *GormxTestSuite) TestFindByIdsMapT() {
+	users, err := FindByIdsMapT(suite.db, []int64{1, 2, 3}, func(user *User) int64 {
+		return user.Id
+	})
+	if suite.Assert().Nil(err) && suite.Equal(2, len(users)) {
+		suite.Equal("hello 0", users[1].Nickname)
+		suite.Equal("hello 1", users[2].Nickname)
+		suite.NotContains(users, int64(3))
+	}
+
+	users, err = FindByIdsMapT(suite.db, nil, func(user *User) int64 {
+		return user.Id
+	})
+	if suite.Assert().Nil(err) {
+		suite.Empty(users)
+	}
+}
+
+func (suite *GormxTestSuite) TestFindPageResult() {
+	if !suite.Assert().Nil(suite.db.Insert(&User{Nickname: "hello 2", Age: 2})) {
+		return
+	}
+
+	result, err := FindPageResult[User](suite.db, 2, 2, OrderBy("id", false))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, result.Total)
+		suite.Equal(2, result.Page)
+		suite.Equal(2, result.Size)
+		suite.Equal(2, result.TotalPages)
+		if suite.Equal(1, len(result.Items)) {
+			suite.Equal("hello 2", result.Items[0].Nickname)
+		}
+	}
+
+	result, err = FindPageResult[User](suite.db, 0, 0, Where("age > ?", 10))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(0, result.Total)
+		suite.Equal(1, result.Page)
+		suite.Equal(defaultPageSize, result.Size)
+		suite.Equal(0, result.TotalPages)
+		suite.Empty(result.Items)
+	}
+
+	result, err = FindPageResult[User](suite.db, 1, 2, Select("nickname"))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(result.Items)) {
+		suite.EqualValues(3, result.Total)
+		suite.Equal(2, result.TotalPages)
+		suite.Equal("hello 0", result.Items[0].Nickname)
+		suite.EqualValues(0, result.Items[0].Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestKeyset() {
+	var users []User
+	err := suite.db.FindMany(&users, Keyset("id", nil, 1, false))
+	if !suite.Assert().Nil(err) || !suite.Equal(1, len(users)) {
+		return
+	}
+	suite.EqualValues(1, users[0].Id)
+
+	lastId := users[0].Id
+	users = nil
+	err = suite.db.FindMany(&users, Keyset("id", lastId, 1, false))
+	if !suite.Assert().Nil(err) || !suite.Equal(1, len(users)) {
+		return
+	}
+	suite.EqualValues(2, users[0].Id)
+
+	lastId = users[0].Id
+	users = nil
+	err = suite.db.FindMany(&users, Keyset("id", lastId, 1, false))
+	if suite.Assert().Nil(err) {
+		suite.Equal(0, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Keyset("id", int64(2), 10, true))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(1, users[0].Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestFirstOrCreate() {
+	var user User
+	created, err := suite.db.FirstOrCreate(&user, Where(map[string]interface{}{"nickname": "hello first"}))
+	if suite.Assert().Nil(err) {
+		suite.True(created)
+		suite.EqualValues(3, user.Id)
+		suite.Equal("hello first", user.Nickname)
+	}
+
+	var found User
+	created, err = suite.db.FirstOrCreate(&found, Where(map[string]interface{}{"nickname": "hello first"}))
+	if suite.Assert().Nil(err) {
+		suite.False(created)
+		suite.EqualValues(user.Id, found.Id)
+	}
+
+	total, err := suite.db.Model(&User{}).Count()
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestFirstOrInit() {
+	var user User
+	found, err := suite.db.FirstOrInit(&user, Where(map[string]interface{}{"nickname": "hello init", "age": 18}))
+	if suite.Assert().Nil(err) {
+		suite.False(found)
+		suite.EqualValues(0, user.Id)
+		suite.Equal("hello init", user.Nickname)
+		suite.EqualValues(18, user.Age)
+	}
+
+	total, err := suite.db.Model(&User{}).Count()
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+
+	user = User{}
+	found, err = suite.db.FirstOrInit(&user, Where(map[string]interface{}{"nickname": "hello 1"}))
+	if suite.Assert().Nil(err) {
+		suite.True(found)
+		suite.EqualValues(2, user.Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestReturning() {
+	if suite.db.DB().Dialector.Name() == "mysql" {
+		suite.T().Skip("mysql does not support RETURNING")
+	}
+
+	user := User{Nickname: "hello returning", Age: 5}
+	if suite.Assert().Nil(suite.db.Insert(&user, Returning("id", "age"))) {
+		suite.EqualValues(3, user.Id)
+		suite.EqualValues(5, user.Age)
+	}
+
+	user = User{Id: 1}
+	err := suite.db.Model(&user).Update("age", gorm.Expr("age + ?", 10), Returning("nickname", "age"))
+	if suite.Assert().Nil(err) {
+		suite.Equal("hello 0", user.Nickname)
+		suite.EqualValues(10, user.Age)
+	}
+
+	var deleted []User
+	_, err = suite.db.DeleteN(&deleted, Where("age > ?", 5), Returning("id"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(deleted)) {
+		suite.EqualValues(1, deleted[0].Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestInsertIgnore() {
+	inserted, err := suite.db.InsertIgnore(&User{Id: 1, Nickname: "hello ignore"}, "id")
+	if suite.Assert().Nil(err) {
+		suite.False(inserted)
+		var user User
+		if suite.Assert().Nil(suite.db.FindOne(&user, WithId(1))) {
+			suite.Equal("hello 0", user.Nickname)
+		}
+	}
+
+	inserted, err = suite.db.InsertIgnore(&User{Id: 100, Nickname: "hello ignore"}, "id")
+	if suite.Assert().Nil(err) {
+		suite.True(inserted)
+	}
+}
+
+func (suite *GormxTestSuite) TestUpsert() {
+	err := suite.db.Upsert(&User{Id: 1, Nickname: "hello upsert", Age: 100}, []string{"id"}, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		var user User
+		err = suite.db.FindOne(&user, WithId(1))
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello upsert", user.Nickname)
+			suite.EqualValues(0, user.Age)
+		}
+	}
+
+	err = suite.db.Upsert(&User{Id: 2, Nickname: "hello upsert all", Age: 100}, []string{"id"}, nil)
+	if suite.Assert().Nil(err) {
+		var user User
+		err = suite.db.FindOne(&user, WithId(2))
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello upsert all", user.Nickname)
+			suite.EqualValues(100, user.Age)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestUpsertReturning() {
+	user := User{Id: 1, Nickname: "hello returning", Age: 100}
+	err := suite.db.UpsertReturning(&user, []string{"id"}, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, user.Id)
+		suite.Equal("hello returning", user.Nickname)
+		suite.EqualValues(0, user.Age)
+	}
+
+	user = User{Id: 3, Nickname: "hello new", Age: 3}
+	err = suite.db.UpsertReturning(&user, []string{"id"}, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, user.Id)
+		suite.EqualValues(3, user.Age)
+	}
+}
+
+func (suite *GormxTestSuite) TestUpsertReturningWithoutReturning() {
+	db, err := New(&Config{Dialector: suite.db.cfg.Dialector})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	// 去掉 RETURNING，模拟 mysql 等不支持 RETURNING 的数据库，插入后重新查询
+	create := db.DB().Callback().Create()
+	var clauses []string
+	for _, name := range create.Clauses {
+		if name != "RETURNING" {
+			clauses = append(clauses, name)
+		}
+	}
+	create.Clauses = clauses
+	suite.Require().Nil(create.Replace("gorm:create", callbacks.Create(&callbacks.Config{CreateClauses: clauses})))
+	suite.False(supportsReturning(db.DB()))
+
+	user := User{Id: 1, Nickname: "hello fallback", Age: 100}
+	err = db.UpsertReturning(&user, []string{"id"}, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, user.Id)
+		suite.Equal("hello fallback", user.Nickname)
+		suite.EqualValues(0, user.Age)
+	}
+
+	// 自增主键在插入后才有值
+	user = User{Nickname: "hello auto", Age: 7}
+	err = db.UpsertReturning(&user, []string{"id"}, nil)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, user.Id)
+		suite.Equal("hello auto", user.Nickname)
+		suite.EqualValues(7, user.Age)
+	}
+
+	// 没有 conflictColumns 时使用主键重新查询
+	user = User{Id: 2, Nickname: "hello primary", Age: 100}
+	err = db.UpsertReturning(&user, nil, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, user.Id)
+		suite.Equal("hello primary", user.Nickname)
+		suite.EqualValues(1, user.Age)
+	}
+
+	// 默认条件同样用于重新查询
+	suite.Require().Nil(db.Exec("create table test_users_archive (id integer primary key autoincrement not null, nickname varchar(64) not null, age integer default 0);"))
+	defer db.Exec("drop table test_users_archive;")
+	user = User{Id: 1, Nickname: "hello archive", Age: 9}
+	err = db.WithDefaultScopes(Table("test_users_archive")).UpsertReturning(&user, []string{"id"}, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, user.Id)
+		suite.Equal("hello archive", user.Nickname)
+		suite.EqualValues(9, user.Age)
+	}
+
+	// 冲突字段为空值时不能重新查询
+	suite.Require().Nil(db.Exec("create unique index idx_test_users_nickname on test_users (nickname);"))
+	err = db.UpsertReturning(&User{Nickname: "", Age: 1}, []string{"nickname"}, nil)
+	if suite.Assert().NotNil(err) {
+		suite.Contains(err.Error(), "Nickname is empty")
+	}
+}
+
+func (suite *GormxTestSuite) TestUpsertWhere() {
+	if suite.db.DB().Dialector.Name() == "mysql" {
+		suite.T().Skip("mysql does not support ON CONFLICT ... WHERE")
+	}
+
+	where := "test_users.age < excluded.age"
+	err := suite.db.UpsertWhere(&User{Id: 1, Nickname: "hello newer", Age: 5}, []string{"id"}, nil, where)
+	if suite.Assert().Nil(err) {
+		var user User
+		if suite.Assert().Nil(suite.db.FindOne(&user, WithId(1))) {
+			suite.Equal("hello newer", user.Nickname)
+			suite.EqualValues(5, user.Age)
+		}
+	}
+
+	err = suite.db.UpsertWhere(&User{Id: 1, Nickname: "hello older", Age: 3}, []string{"id"}, nil, where)
+	if suite.Assert().Nil(err) {
+		var user User
+		if suite.Assert().Nil(suite.db.FindOne(&user, WithId(1))) {
+			suite.Equal("hello newer", user.Nickname)
+			suite.EqualValues(5, user.Age)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestUpsertMany() {
+	users := []User{
+		{Id: 1, Nickname: "hello upsert 0", Age: 10},
+		{Id: 2, Nickname: "hello upsert 1", Age: 11},
+		{Id: 3, Nickname: "hello upsert 2", Age: 12},
+	}
+	err := suite.db.UpsertMany(users, []string{"id"}, []string{"nickname"}, 2)
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	var found []User
+	err = suite.db.FindMany(&found, OrderBy("id", false))
+	if suite.Assert().Nil(err) && suite.Equal(3, len(found)) {
+		suite.Equal("hello upsert 0", found[0].Nickname)
+		suite.EqualValues(0, found[0].Age)
+		suite.Equal("hello upsert 1", found[1].Nickname)
+		suite.EqualValues(1, found[1].Age)
+		suite.Equal("hello upsert 2", found[2].Nickname)
+		suite.EqualValues(12, found[2].Age)
+	}
+}
+
+func (suite *GormxTestSuite) TestInsertInBatches() {
+	users := make([]User, 1000)
+	for i := range users {
+		users[i] = User{
+			Nickname: fmt.Sprintf("hello batch %d", i),
+			Age:      int64(i),
+		}
+	}
+	err := suite.db.InsertInBatches(users, 100)
+	if suite.Assert().Nil(err) {
+		total, err := suite.db.Model(&User{}).Count()
+		if suite.Assert().Nil(err) {
+			suite.EqualValues(1002, total)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestFindInBatches() {
+	var (
+		users   []User
+		sum     int64
+		batches int
+	)
+	err := suite.db.FindInBatches(&users, 1, func(tx *Gormx, batch int) error {
+		for _, user := range users {
+			sum += user.Age
+		}
+		batches = batch
+		return nil
+	})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, sum)
+		suite.Equal(2, batches)
+	}
+
+	errAbort := errors.New("abort")
+	batches = 0
+	err = suite.db.FindInBatches(&users, 1, func(tx *Gormx, batch int) error {
+		batches = batch
+		return errAbort
+	})
+	suite.ErrorIs(err, errAbort)
+	suite.Equal(1, batches)
+}
+
+func (suite *GormxTestSuite) TestIterate() {
+	var nicknames []string
+	err := suite.db.Model(&User{}).Iterate(func(scan func(dest interface{}) error) error {
+		var user User
+		if err := scan(&user); err != nil {
+			return err
+		}
+		nicknames = append(nicknames, user.Nickname)
+		return nil
+	}, OrderBy("id", false))
+	if suite.Assert().Nil(err) {
+		suite.Equal([]string{"hello 0", "hello 1"}, nicknames)
+	}
+
+	errAbort := errors.New("abort")
+	rows := 0
+	err = suite.db.Model(&User{}).Iterate(func(scan func(dest interface{}) error) error {
+		rows++
+		return errAbort
+	})
+	suite.ErrorIs(err, errAbort)
+	suite.Equal(1, rows)
+
+	// 提前返回后连接已经释放，可以继续执行查询
+	total, err := suite.db.Model(&User{}).Count()
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestAggregate() {
+	sum, err := suite.db.Model(&User{}).Sum("age")
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, sum)
+	}
+
+	max, err := suite.db.Model(&User{}).Max("age")
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, max)
+	}
+
+	min, err := suite.db.Model(&User{}).Min("age", WithId(2))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, min)
+	}
+
+	avg, err := suite.db.Model(&User{}).Avg("age", WithId(-1))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(0, avg)
+	}
+}
+
+func (suite *GormxTestSuite) TestClone() {
+	suite.NotNil(suite.db.Model(&User{}).cfg)
+	suite.NotNil(suite.db.WithContext(context.Background()).cfg)
+	suite.NotNil(suite.db.Raw("select 1").cfg)
+	suite.Equal(suite.db.cfg, suite.db.Debug().cfg)
+}
+
+func (suite *GormxTestSuite) TestWithConnKeepsConfig() {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "tx")
+	db := suite.db.WithContext(ctx).WithDefaultScopes(Where("age > ?", 0))
+
+	err := db.Tx(func(tx *Gormx) error {
+		suite.Equal(suite.db.cfg, tx.cfg)
+		suite.Equal(1, len(tx.scopes))
+		suite.Equal("tx", tx.context().Value(ctxKey{}))
+		return nil
+	})
+	suite.Nil(err)
+}
+
+func (suite *GormxTestSuite) TestIsNotFound() {
+	var user User
+	err := suite.db.FindOne(&user, WithId(-1))
+	suite.True(IsNotFound(err))
+	suite.ErrorIs(err, ErrNotFound)
+
+	err = suite.db.FindOne(&user, WithId(1))
+	suite.False(IsNotFound(err))
+}
+
+func (suite *GormxTestSuite) TestNotFoundContext() {
+	var user User
+	err := suite.db.FindOne(&user, WithId(-1))
+	if suite.Error(err) {
+		suite.ErrorIs(err, gorm.ErrRecordNotFound)
+		suite.Contains(err.Error(), "model: gormx.User")
+		suite.Contains(err.Error(), "WHERE id=-1")
+	}
+
+	err = suite.db.Take(&user, Where("nickname = ?", "nobody"))
+	if suite.Error(err) {
+		suite.ErrorIs(err, gorm.ErrRecordNotFound)
+		suite.Contains(err.Error(), "nobody")
+	}
+}
+
+func (suite *GormxTestSuite) TestIsDuplicate() {
+	err := suite.db.Insert(&User{Id: 1, Nickname: "hello duplicate"})
+	suite.True(IsDuplicate(err))
+}
+
+func (suite *GormxTestSuite) TestExistsWithoutModel() {
+	for _, c := range []struct {
+		dest   *User
+		opts   []Option
+		exists bool
+	}{
+		{&User{Id: 1}, nil, true},
+		{&User{}, []Option{WithId(1)}, true},
+		{&User{Id: -1}, nil, false},
+		{&User{}, []Option{WithId(-1)}, false},
+		// 只有主键会作为查询条件，其他非零字段会被忽略
+		{&User{Id: 1, Nickname: "not exists"}, nil, true},
+		{&User{}, nil, true},
+	} {
+		exists, err := suite.db.Exists(c.dest, c.opts...)
+		if suite.Assert().Nil(err) {
+			suite.Equal(c.exists, exists)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestTxWithRetry() {
+	errRetry := errors.New("retry")
+	defer func(fn func(error) bool) {
+		isRetryable = fn
+	}(isRetryable)
+	isRetryable = func(err error) bool {
+		return errors.Is(err, errRetry)
+	}
+
+	var attempts int
+	err := suite.db.TxWithRetry(func(tx *Gormx) error {
+		attempts++
+		return errRetry
+	}, 3)
+	suite.ErrorIs(err, errRetry)
+	suite.Equal(4, attempts)
+
+	attempts = 0
+	err = suite.db.TxWithRetry(func(tx *Gormx) error {
+		attempts++
+		if attempts < 2 {
+			return errRetry
+		}
+		return tx.Model(&User{Id: 1}).Update("nickname", "hello retry")
+	}, 3)
+	if suite.Nil(err) {
+		suite.Equal(2, attempts)
+	}
+
+	errFatal := errors.New("fatal")
+	attempts = 0
+	err = suite.db.TxWithRetry(func(tx *Gormx) error {
+		attempts++
+		return errFatal
+	}, 3)
+	suite.ErrorIs(err, errFatal)
+	suite.Equal(1, attempts)
+}
+
+func (suite *GormxTestSuite) TestNestedTx() {
+	errInner := errors.New("inner rollback")
+	err := suite.db.Tx(func(tx *Gormx) error {
+		if err := tx.Model(&User{Id: 1}).Update("nickname", "hello outer"); err != nil {
+			return err
+		}
+		err := tx.Tx(func(tx *Gormx) error {
+			if err := tx.Model(&User{Id: 2}).Update("nickname", "hello inner"); err != nil {
+				return err
+			}
+			return errInner
+		})
+		suite.ErrorIs(err, errInner)
+		return nil
+	})
+	if suite.Nil(err) {
+		var users []User
+		err = suite.db.FindMany(&users, OrderBy("id", false))
+		if suite.Nil(err) && suite.Equal(2, len(users)) {
+			suite.Equal("hello outer", users[0].Nickname)
+			suite.Equal("hello 1", users[1].Nickname)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestTransactional() {
+	gormTx := suite.db.DB().Begin()
+	if !suite.Assert().Nil(gormTx.Error) {
+		return
+	}
+	defer gormTx.Rollback()
+
+	tx := suite.db.Transactional(gormTx)
+	if !suite.Assert().Nil(tx.Model(&User{Id: 1}).Update("nickname", "hello transactional")) {
+		return
+	}
+
+	var user User
+	if suite.Assert().Nil(tx.FindOne(&user, WithId(1))) {
+		suite.Equal("hello transactional", user.Nickname)
+	}
+
+	suite.Nil(gormTx.Rollback().Error)
+	user = User{}
+	if suite.Assert().Nil(suite.db.FindOne(&user, WithId(1))) {
+		suite.Equal("hello 0", user.Nickname)
+	}
+}
+
+func (suite *GormxTestSuite) TestTxSafe() {
+	var err error
+	suite.NotPanics(func() {
+		err = suite.db.TxSafe(func(tx *Gormx) error {
+			if err := tx.Model(&User{Id: 1}).Update("nickname", "hello panic"); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+	if suite.ErrorIs(err, ErrTxPanic) {
+		suite.Contains(err.Error(), "boom")
+	}
+
+	var user User
+	if suite.Assert().Nil(suite.db.FindOne(&user, WithId(1))) {
+		suite.Equal("hello 0", user.Nickname)
+	}
+
+	suite.Nil(suite.db.TxSafe(func(tx *Gormx) error {
+		return tx.Model(&User{Id: 1}).Update("nickname", "hello safe")
+	}))
+}
+
+func (suite *GormxTestSuite) TestContextMethods() {
+	var users []User
+	if suite.Assert().Nil(suite.db.FindManyContext(context.Background(), &users)) {
+		suite.Equal(2, len(users))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	users = nil
+	suite.ErrorIs(suite.db.FindManyContext(ctx, &users), context.Canceled)
+	suite.Empty(users)
+
+	var user User
+	suite.ErrorIs(suite.db.FindOneContext(ctx, &user, WithId(1)), context.Canceled)
+	suite.ErrorIs(suite.db.InsertContext(ctx, &User{Nickname: "hello canceled"}), context.Canceled)
+	suite.ErrorIs(suite.db.TakeContext(ctx, &user, WithId(1)), context.Canceled)
+	suite.ErrorIs(suite.db.Model(&User{Id: 1}).IncrementContext(ctx, "age", 1), context.Canceled)
+	suite.ErrorIs(suite.db.Raw("select * from test_users").ScanContext(ctx, &users), context.Canceled)
+	_, err := suite.db.Model(&User{}).SumContext(ctx, "age")
+	suite.ErrorIs(err, context.Canceled)
+
+	var ids []int64
+	if suite.Assert().Nil(suite.db.Model(&User{}).PluckContext(context.Background(), "id", &ids, OrderBy("id", false))) {
+		suite.Equal([]int64{1, 2}, ids)
+	}
+
+	total, err := suite.db.Model(&User{}).CountContext(context.Background())
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestConfigContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, err := New(&Config{
+		Dialector: suite.db.cfg.Dialector,
+		Context:   ctx,
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	var users []User
+	if suite.Assert().Nil(db.FindMany(&users)) {
+		suite.Equal(2, len(users))
+	}
+
+	cancel()
+	users = nil
+	suite.ErrorIs(db.FindMany(&users), context.Canceled)
+	suite.ErrorIs(db.Model(&User{Id: 1}).Update("nickname", "hello canceled"), context.Canceled)
+
+	// WithContext 会替换默认的上下文
+	users = nil
+	if suite.Assert().Nil(db.WithContext(context.Background()).FindMany(&users)) {
+		suite.Equal(2, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestTxContextCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := suite.db.WithContext(ctx).Tx(func(tx *Gormx) error {
+		if err := tx.Model(&User{Id: 1}).Update("nickname", "hello canceled"); err != nil {
+			return err
+		}
+		cancel()
+		return tx.Insert(&User{Nickname: "hello canceled insert"})
+	})
+	suite.ErrorIs(err, context.Canceled)
+
+	var user User
+	err = suite.db.FindOne(&user, WithId(1))
+	if suite.Nil(err) {
+		suite.Equal("hello 0", user.Nickname)
+	}
+	total, err := suite.db.Model(&User{}).Count()
+	if suite.Nil(err) {
+		suite.EqualValues(2, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestLock() {
+	err := suite.db.Tx(func(tx *Gormx) error {
+		var user User
+		if err := tx.FindOne(&user, WithId(1), ForUpdate()); err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("age", user.Age+10)
+	})
+	if suite.Nil(err) {
+		var user User
+		err = suite.db.FindOne(&user, WithId(1), Lock("SHARE"))
+		if suite.Nil(err) {
+			suite.EqualValues(10, user.Age)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestLockOptions() {
+	if suite.db.DB().Dialector.Name() == "sqlite" {
+		suite.T().Skip("sqlite does not support row locking")
+	}
+
+	err := suite.db.Tx(func(tx *Gormx) error {
+		var locked User
+		if err := tx.FindOne(&locked, WithId(1), ForUpdate()); err != nil {
+			return err
+		}
+
+		// 另一个事务使用新的连接，跳过已被锁定的记录
+		return suite.db.Tx(func(other *Gormx) error {
+			var user User
+			if err := other.FindOne(&user, ForUpdateSkipLocked()); err != nil {
+				return err
+			}
+			suite.EqualValues(2, user.Id)
+
+			user = User{}
+			suite.NotNil(other.FindOne(&user, WithId(1), ForUpdateNoWait()))
+			return nil
+		})
+	})
+	suite.Nil(err)
+}
+
+func (suite *GormxTestSuite) TestSelect() {
+	var users []User
+	err := suite.db.FindMany(&users, Select("id", "nickname"), OrderBy("id", true), Pagination(1, 1))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(&User{
+			Id:       2,
+			Nickname: "hello 1",
+		}, &users[0])
+	}
+}
+
+func (suite *GormxTestSuite) TestOmit() {
+	user := User{
+		Nickname: "hello omit",
+		Age:      99,
+	}
+	err := suite.db.Insert(&user, Omit("age"))
+	if suite.Assert().Nil(err) {
+		var found User
+		err = suite.db.FindOne(&found, WithId(user.Id))
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello omit", found.Nickname)
+			suite.EqualValues(0, found.Age)
+		}
+	}
+
+	err = suite.db.Updates(&User{Id: 1, Nickname: "hello omit updates", Age: 99}, Omit("nickname"))
+	if suite.Assert().Nil(err) {
+		var found User
+		err = suite.db.FindOne(&found, WithId(1))
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello 0", found.Nickname)
+			suite.EqualValues(99, found.Age)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestPreload() {
+	suite.initOrders()
+	defer suite.dropOrders()
+
+	var order Order
+	err := suite.db.FindOne(&order, WithId(1), Preload("Items"))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(order.Items)) {
+		suite.Equal("apple", order.Items[0].Name)
+		suite.Equal("banana", order.Items[1].Name)
+	}
+
+	var orders []Order
+	err = suite.db.FindMany(&orders, Preload("Items", Where("name <> ?", "banana")), OrderBy("id", false))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(orders)) {
+		suite.Equal(1, len(orders[0].Items))
+		suite.Equal(1, len(orders[1].Items))
+	}
+
+	// 同一个 Option 可以在多个 goroutine 中同时使用
+	withItems := Preload("Items", Where("name <> ?", "banana"))
+	errs := make(chan error, 4)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var orders []Order
+			errs <- suite.db.FindMany(&orders, withItems)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		suite.Nil(err)
+	}
+}
+
+func (suite *GormxTestSuite) TestFullSaveAssociations() {
+	suite.initOrders()
+	defer suite.dropOrders()
+
+	var order Order
+	if !suite.Assert().Nil(suite.db.FindOne(&order, WithId(1), Preload("Items"))) {
+		return
+	}
+	order.Items[0].Name = "apricot"
+	order.Items = append(order.Items, OrderItem{Name: "grape"})
+	if !suite.Assert().Nil(suite.db.Save(&order, FullSaveAssociations())) {
+		return
+	}
+
+	var items []OrderItem
+	err := suite.db.FindMany(&items, Where("order_id = ?", 1), OrderBy("id", false))
+	if suite.Assert().Nil(err) && suite.Equal(3, len(items)) {
+		suite.Equal("apricot", items[0].Name)
+		suite.Equal("banana", items[1].Name)
+		suite.Equal("grape", items[2].Name)
+	}
+}
+
+func (suite *GormxTestSuite) TestJoins() {
+	suite.initOrders()
+	defer suite.dropOrders()
+
+	var users []User
+	err := suite.db.FindMany(&users,
+		Joins("JOIN test_orders ON test_orders.user_id = test_users.id"),
+		Joins("JOIN test_order_items ON test_order_items.order_id = test_orders.id"),
+		Where("test_order_items.name = ?", "cherry"),
+	)
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(2, users[0].Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestDistinct() {
+	err := suite.db.Insert([]User{
+		{Nickname: "hello 2", Age: 1},
+		{Nickname: "hello 3", Age: 1},
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	var ages []int64
+	err = suite.db.Model(&User{}).Pluck("age", &ages, Distinct("age"), OrderBy("age", false))
+	if suite.Assert().Nil(err) {
+		suite.Equal([]int64{0, 1}, ages)
+	}
+
+	var users []User
+	err = suite.db.FindMany(&users, Distinct("age"))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestUnscoped() {
+	suite.initSoftUsers()
+	defer suite.dropSoftUsers()
+
+	err := suite.db.Delete(&SoftUser{Id: 1})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	var users []SoftUser
+	err = suite.db.FindMany(&users)
+	if suite.Assert().Nil(err) {
+		suite.Equal(1, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Unscoped())
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	total, err := suite.db.Model(&SoftUser{}).Count(Unscoped())
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+
+	err = suite.db.Delete(&SoftUser{Id: 1}, Unscoped())
+	if suite.Assert().Nil(err) {
+		total, err = suite.db.Model(&SoftUser{}).Count(Unscoped())
+		if suite.Assert().Nil(err) {
+			suite.EqualValues(1, total)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestCountWithDeleted() {
+	suite.initSoftUsers()
+	defer suite.dropSoftUsers()
+
+	if !suite.Assert().Nil(suite.db.Delete(&SoftUser{Id: 1})) {
+		return
+	}
+
+	total, err := suite.db.Model(&SoftUser{}).Count()
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, total)
+	}
+
+	total, err = suite.db.Model(&SoftUser{}).CountWithDeleted()
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestRestore() {
+	suite.initSoftUsers()
+	defer suite.dropSoftUsers()
+
+	if !suite.Assert().Nil(suite.db.Delete(&SoftUser{Id: 1})) {
+		return
+	}
+
+	var user SoftUser
+	suite.True(IsNotFound(suite.db.FindOne(&user, WithId(1))))
+
+	if suite.Assert().Nil(suite.db.Restore(&SoftUser{Id: 1})) {
+		user = SoftUser{}
+		if suite.Assert().Nil(suite.db.FindOne(&user, WithId(1))) {
+			suite.Equal("hello 0", user.Nickname)
+		}
+	}
+
+	suite.Equal(ErrNoRowsAffected, suite.db.Restore(&SoftUser{Id: 2}))
+
+	// 没有软删除字段
+	err := suite.db.Restore(&User{Id: 1})
+	if suite.Assert().NotNil(err) {
+		suite.Contains(err.Error(), "gorm.DeletedAt")
+	}
+}
+
+func (suite *GormxTestSuite) TestRestoreCustomColumn() {
+	type RemovedUser struct {
+		Id        int64
+		Nickname  string
+		RemovedAt gorm.DeletedAt `gorm:"column:removed_at"`
+	}
+	suite.Require().Nil(suite.db.Exec("create table removed_users (id integer primary key autoincrement not null, nickname varchar(64) not null, removed_at timestamp null);"))
+	defer suite.db.Exec("drop table removed_users;")
+
+	if !suite.Assert().Nil(suite.db.Insert(&RemovedUser{Nickname: "hello removed"})) ||
+		!suite.Assert().Nil(suite.db.Delete(&RemovedUser{Id: 1})) {
+		return
+	}
+	var user RemovedUser
+	suite.True(IsNotFound(suite.db.FindOne(&user, WithId(1))))
+
+	if suite.Assert().Nil(suite.db.Restore(&RemovedUser{Id: 1})) {
+		suite.Nil(suite.db.FindOne(&user, WithId(1)))
+	}
+	suite.Equal(ErrNoRowsAffected, suite.db.Restore(&RemovedUser{Id: 1}))
+}
+
+func (suite *GormxTestSuite) TestWithIds() {
+	var users []User
+	err := suite.db.FindMany(&users, WithIds(1, 2))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, WithIds())
+	if suite.Assert().Nil(err) {
+		suite.Equal(0, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestByPrimaryKey() {
+	type TenantCode struct {
+		TenantId int64  `gorm:"primaryKey"`
+		Code     string `gorm:"primaryKey"`
+		Name     string
+	}
+
+	suite.db.Exec("create table tenant_codes (tenant_id integer not null, code varchar(64) not null, name varchar(64) not null, primary key (tenant_id, code));")
+	defer suite.db.Exec("drop table tenant_codes;")
+	suite.Assert().Nil(suite.db.Insert([]TenantCode{
+		{TenantId: 1, Code: "a", Name: "tenant 1 a"},
+		{TenantId: 2, Code: "a", Name: "tenant 2 a"},
+	}))
+
+	var code TenantCode
+	err := suite.db.FindOne(&code, ByPrimaryKey(&TenantCode{}, map[string]interface{}{"tenant_id": 2, "Code": "a"}))
+	if suite.Assert().Nil(err) {
+		suite.Equal("tenant 2 a", code.Name)
+	}
+
+	err = suite.db.FindOne(&TenantCode{}, ByPrimaryKey(&TenantCode{}, map[string]interface{}{"tenant_id": 2}))
+	suite.NotNil(err)
+}
+
+func (suite *GormxTestSuite) TestTable() {
+	suite.db.Exec("create table test_users_shard (id integer primary key autoincrement not null, nickname varchar(64) not null, age integer default 0);")
+	defer suite.db.Exec("drop table test_users_shard;")
+	suite.Assert().Nil(suite.db.Insert(&User{Nickname: "hello shard", Age: 10}, Table("test_users_shard")))
+
+	var users []User
+	err := suite.db.FindMany(&users, Table("test_users_shard"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello shard", users[0].Nickname)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users)
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestFindOneOptional() {
+	var user User
+	found, err := suite.db.FindOneOptional(&user, WithId(1))
+	if suite.Assert().Nil(err) {
+		suite.True(found)
+		suite.Equal("hello 0", user.Nickname)
+	}
+
+	found, err = suite.db.FindOneOptional(&User{}, WithId(-1))
+	if suite.Assert().Nil(err) {
+		suite.False(found)
+	}
+}
+
+func (suite *GormxTestSuite) TestIncrement() {
+	err := suite.db.Model(&User{Id: 1}).Increment("age", 5)
+	if suite.Assert().Nil(err) {
+		var user User
+		err = suite.db.FindOne(&user, WithId(1))
+		if suite.Assert().Nil(err) {
+			suite.EqualValues(5, user.Age)
+		}
+	}
+
+	err = suite.db.Model(&User{Id: 1}).Decrement("age", 2)
+	if suite.Assert().Nil(err) {
+		var user User
+		err = suite.db.FindOne(&user, WithId(1))
+		if suite.Assert().Nil(err) {
+			suite.EqualValues(3, user.Age)
+		}
+	}
+
+	err = suite.db.Model(&User{Id: -1}).Increment("age", 1)
+	suite.ErrorIs(err, ErrNoRowsAffected)
+}
+
+type TimedUser struct {
+	Id        int64
+	Nickname  string
+	UpdatedAt time.Time
+}
+
+func (TimedUser) TableName() string {
+	return "test_timed_users"
+}
+
+func (suite *GormxTestSuite) TestUpdatesSelectZero() {
+	// 不指定 Select 时零值字段会被忽略
+	err := suite.db.Updates(&User{Id: 2, Nickname: "hello zero", Age: 0})
+	if suite.Assert().Nil(err) {
+		var user User
+		if suite.Assert().Nil(suite.db.FindOne(&user, WithId(2))) {
+			suite.Equal("hello zero", user.Nickname)
+			suite.EqualValues(1, user.Age)
+		}
+	}
+
+	err = suite.db.Updates(&User{Id: 2, Age: 0}, Select("age"))
+	if suite.Assert().Nil(err) {
+		var user User
+		if suite.Assert().Nil(suite.db.FindOne(&user, WithId(2))) {
+			suite.Equal("hello zero", user.Nickname)
+			suite.EqualValues(0, user.Age)
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestUpdateBatch() {
+	users := []User{
+		{Id: 1, Nickname: "hello batch 0", Age: 10},
+		{Id: 2, Nickname: "hello batch 1", Age: 11},
+	}
+	err := suite.db.UpdateBatch(users, []string{"nickname"})
+	if suite.Assert().Nil(err) {
+		var result []User
+		if suite.Assert().Nil(suite.db.FindMany(&result, OrderBy("id", false))) && suite.Equal(2, len(result)) {
+			suite.Equal("hello batch 0", result[0].Nickname)
+			suite.Equal("hello batch 1", result[1].Nickname)
+			// 没有指定的字段不会更新
+			suite.EqualValues(0, result[0].Age)
+			suite.EqualValues(1, result[1].Age)
+		}
+	}
+
+	suite.Equal(ErrNoRowsAffected, suite.db.UpdateBatch([]User{{Id: -1, Nickname: "hello"}}, []string{"nickname"}))
+	suite.NotNil(suite.db.UpdateBatch([]User{{Nickname: "hello"}}, []string{"nickname"}))
+}
+
+func (suite *GormxTestSuite) TestUpdateColumns() {
+	suite.db.Exec("create table test_timed_users (id integer primary key autoincrement not null, nickname varchar(64) not null, updated_at timestamp not null);")
+	defer suite.db.Exec("drop table test_timed_users;")
+
+	updatedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := suite.db.Insert(&TimedUser{Nickname: "hello", UpdatedAt: updatedAt})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	err = suite.db.Model(&TimedUser{Id: 1}).UpdateColumns(map[string]interface{}{"nickname": "hello columns"})
+	if suite.Assert().Nil(err) {
+		var user TimedUser
+		err = suite.db.FindOne(&user, WithId(1))
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello columns", user.Nickname)
+			suite.True(updatedAt.Equal(user.UpdatedAt))
+		}
+	}
+
+	err = suite.db.Model(&TimedUser{Id: 1}).Updates(map[string]interface{}{"nickname": "hello updates"})
+	if suite.Assert().Nil(err) {
+		var user TimedUser
+		err = suite.db.FindOne(&user, WithId(1))
+		if suite.Assert().Nil(err) {
+			suite.False(updatedAt.Equal(user.UpdatedAt))
+		}
+	}
+
+	err = suite.db.Model(&TimedUser{Id: -1}).UpdateColumns(map[string]interface{}{"nickname": "hello"})
+	suite.ErrorIs(err, ErrNoRowsAffected)
+}
+
+func (suite *GormxTestSuite) TestPing() {
+	suite.Nil(suite.db.Ping(context.Background()))
+	suite.Equal(10, suite.db.Stats().MaxOpenConnections)
+}
+
+func (suite *GormxTestSuite) TestSQLDB() {
+	sqlDb, err := suite.db.SQLDB()
+	if suite.Assert().Nil(err) {
+		suite.Nil(sqlDb.PingContext(context.Background()))
+		suite.Equal(10, sqlDb.Stats().MaxOpenConnections)
+	}
+}
+
+func (suite *GormxTestSuite) TestClose() {
+	db, err := New(suite.db.cfg)
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	suite.Nil(db.Close())
+	suite.NotPanics(func() {
+		_ = db.Close()
+	})
+
+	var user User
+	err = db.FindOne(&user, WithId(1))
+	suite.NotNil(err)
+	suite.NotNil(db.Ping(context.Background()))
+}
+
+func (suite *GormxTestSuite) TestConfigPagination() {
+	users := make([]User, 60)
+	for i := range users {
+		users[i] = User{Nickname: fmt.Sprintf("hello page %d", i)}
+	}
+	if !suite.Assert().Nil(suite.db.Insert(users)) {
+		return
+	}
+
+	db := NewWithDB(suite.db.DB())
+	db.cfg = &Config{
+		DefaultPageSize: 5,
+		MaxPageSize:     50,
+	}
+
+	var found []User
+	err := db.FindMany(&found, db.Pagination(1, 500))
+	if suite.Assert().Nil(err) {
+		suite.Equal(50, len(found))
+	}
+
+	found = nil
+	err = db.FindMany(&found, db.Pagination(1, 0))
+	if suite.Assert().Nil(err) {
+		suite.Equal(5, len(found))
+	}
+
+	found = nil
+	total, err := db.FindPage(&found, 1, 500)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(62, total)
+		suite.Equal(50, len(found))
+	}
+
+	found = nil
+	err = db.FindMany(&found, db.Keyset("id", nil, 500, false))
+	if suite.Assert().Nil(err) {
+		suite.Equal(50, len(found))
+	}
+
+	found = nil
+	err = db.FindMany(&found, db.Keyset("id", nil, 0, false))
+	if suite.Assert().Nil(err) {
+		suite.Equal(5, len(found))
+	}
+
+	found = nil
+	err = suite.db.FindMany(&found, suite.db.Pagination(1, 0))
+	if suite.Assert().Nil(err) {
+		suite.Equal(20, len(found))
+	}
+}
+
+type captureLogger struct {
+	level logger.LogLevel
+	sqls  []string
+	warns []string
+}
+
+func (l *captureLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l.level = level
+	return l
+}
+
+func (l *captureLogger) Info(context.Context, string, ...interface{}) {}
+
+func (l *captureLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.warns = append(l.warns, fmt.Sprintf(msg, args...))
+}
+
+func (l *captureLogger) Error(context.Context, string, ...interface{}) {}
+
+func (l *captureLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	l.sqls = append(l.sqls, sql)
+}
+
+func (suite *GormxTestSuite) TestLogger() {
+	l := &captureLogger{}
+	db, err := New(&Config{
+		Dialector: suite.db.cfg.Dialector,
+		Logger:    l,
+		Debug:     true,
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	var user User
+	err = db.FindOne(&user, WithId(1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(logger.Info, l.level)
+		if suite.Equal(1, len(l.sqls)) {
+			suite.Contains(l.sqls[0], "test_users")
+		}
+	}
+}
+
+func (suite *GormxTestSuite) TestPrepareStmt() {
+	db, err := New(&Config{
+		Dialector:   suite.db.cfg.Dialector,
+		PrepareStmt: true,
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	for i := 0; i < 10; i++ {
+		var user User
+		if !suite.Assert().Nil(db.FindOne(&user, WithId(int64(i%2+1)))) {
+			return
+		}
+		suite.EqualValues(i%2+1, user.Id)
+	}
+
+	err = db.Tx(func(tx *Gormx) error {
+		var user User
+		return tx.FindOne(&user, WithId(1))
+	})
+	suite.Nil(err)
+
+	if pool, ok := db.DB().ConnPool.(*gorm.PreparedStmtDB); suite.True(ok) {
+		suite.Equal(1, len(pool.Stmts))
+	}
+}
+
+func (suite *GormxTestSuite) TestSkipDefaultTransaction() {
+	for _, skip := range []bool{false, true} {
+		db, err := New(&Config{
+			Dialector:              suite.db.cfg.Dialector,
+			SkipDefaultTransaction: skip,
+		})
+		if !suite.Assert().Nil(err) {
+			return
+		}
+
+		var inTx bool
+		err = db.RegisterCreateCallback("test:check_tx", CallbackBefore, func(db *gorm.DB) {
+			_, inTx = db.Statement.ConnPool.(gorm.TxCommitter)
+		})
+		if !suite.Assert().Nil(err) {
+			return
+		}
+
+		user := User{Nickname: "hello skip tx"}
+		if suite.Assert().Nil(db.Insert(&user)) {
+			suite.NotZero(user.Id)
+			suite.Equal(!skip, inTx)
+		}
+		suite.Nil(db.Close())
+	}
+}
+
+func (suite *GormxTestSuite) TestSlowThreshold() {
+	for _, c := range []struct {
+		threshold time.Duration
+		warns     int
+	}{
+		{time.Nanosecond, 1},
+		{time.Hour, 0},
+		{0, 0},
+		{-1, 0},
+	} {
+		l := &captureLogger{}
+		db, err := New(&Config{
+			Dialector:     suite.db.cfg.Dialector,
+			Logger:        l,
+			SlowThreshold: c.threshold,
+		})
+		if !suite.Assert().Nil(err) {
+			return
+		}
+
+		var user User
+		err = db.FindOne(&user, WithId(1))
+		if suite.Assert().Nil(err) && suite.Equal(c.warns, len(l.warns)) && c.warns > 0 {
+			suite.Contains(l.warns[0], "SLOW SQL")
+			suite.Contains(l.warns[0], "test_users")
+		}
+		suite.Nil(db.Close())
+	}
+}
+
+type countPlugin struct {
+	initialized int
+}
+
+func (p *countPlugin) Name() string {
+	return "test:count"
+}
+
+func (p *countPlugin) Initialize(db *gorm.DB) error {
+	p.initialized++
+	return RegisterAround(db, "count", func(string) func(*gorm.DB) {
+		return func(*gorm.DB) {}
+	}, func(string) func(*gorm.DB) {
+		return func(*gorm.DB) {}
+	})
+}
+
+func (suite *GormxTestSuite) TestPlugins() {
+	plugin := &countPlugin{}
+	db, err := New(&Config{
+		Dialector: suite.db.cfg.Dialector,
+		Plugins:   []gorm.Plugin{plugin},
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+	suite.Equal(1, plugin.initialized)
+	suite.NotNil(db.DB().Callback().Query().Get("gormx:count_before_query"))
+	suite.NotNil(db.DB().Callback().Row().Get("gormx:count_after_row"))
+
+	// 重复注册同名插件
+	_, err = New(&Config{
+		Dialector: suite.db.cfg.Dialector,
+		Plugins:   []gorm.Plugin{plugin, plugin},
+	})
+	suite.NotNil(err)
+
+	// 插件注册失败时关闭已经打开的连接池
+	failing := &failPlugin{}
+	_, err = New(&Config{
+		Dialector: suite.db.cfg.Dialector,
+		Plugins:   []gorm.Plugin{failing},
+	})
+	if suite.Assert().NotNil(err) && suite.Assert().NotNil(failing.sqlDb) {
+		suite.EqualError(failing.sqlDb.Ping(), "sql: database is closed")
+	}
+}
+
+type failPlugin struct {
+	sqlDb *sql.DB
+}
+
+func (p *failPlugin) Name() string {
+	return "test:fail"
+}
+
+func (p *failPlugin) Initialize(db *gorm.DB) error {
+	p.sqlDb, _ = db.DB()
+	return errors.New("initialize failed")
+}
+
+func (suite *GormxTestSuite) TestReplicas() {
+	db, err := New(&Config{
+		Dialector:   suite.db.cfg.Dialector,
+		Replicas:    []gorm.Dialector{suite.db.cfg.Dialector},
+		MaxOpenConn: 5,
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	var user User
+	err = db.FindOne(&user, WithId(1))
+	if suite.Assert().Nil(err) {
+		suite.Equal("hello 0", user.Nickname)
+	}
+
+	err = db.Model(&User{Id: 1}).Update("nickname", "hello primary")
+	if suite.Assert().Nil(err) {
+		err = db.FindOne(&user, WithId(1), UsePrimary())
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello primary", user.Nickname)
+		}
+	}
+}
+
+// TestReplicaRouting 主库和副本使用不同的 sqlite 文件，根据查询结果判断语句在哪个库执行
+func TestReplicaRouting(t *testing.T) {
+	dir := t.TempDir()
+	createUsers := "create table test_users (id integer primary key autoincrement not null, nickname varchar(64) not null, age integer default 0);"
+	for _, name := range []string{"primary", "replica"} {
+		db, err := New(&Config{Dialector: sqlite.Open(filepath.Join(dir, name+".db"))})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Nil(t, db.Exec(createUsers))
+		assert.Nil(t, db.Insert(&User{Nickname: "hello " + name}))
+		assert.Nil(t, db.Close())
+	}
+
+	db, err := New(&Config{
+		Dialector: sqlite.Open(filepath.Join(dir, "primary.db")),
+		Replicas:  []gorm.Dialector{sqlite.Open(filepath.Join(dir, "replica.db"))},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	// 读操作使用副本
+	var user User
+	if assert.Nil(t, db.FindOne(&user, WithId(1))) {
+		assert.Equal(t, "hello replica", user.Nickname)
+	}
+	var nicknames []string
+	if assert.Nil(t, db.Model(&User{}).Pluck("nickname", &nicknames)) {
+		assert.Equal(t, []string{"hello replica"}, nicknames)
+	}
+
+	// 写操作使用主库，副本中没有新写入的记录
+	if !assert.Nil(t, db.Insert(&User{Nickname: "hello insert"})) ||
+		!assert.Nil(t, db.Model(&User{Id: 1}).Update("age", 10)) {
+		return
+	}
+	total, err := db.Model(&User{}).Count()
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, 1, total)
+	}
+	if assert.Nil(t, db.FindOne(&user, WithId(1))) {
+		assert.EqualValues(t, 0, user.Age)
+	}
+
+	// UsePrimary 强制从主库读取
+	total, err = db.Model(&User{}).Count(UsePrimary())
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, 2, total)
+	}
+	user = User{}
+	if assert.Nil(t, db.FindOne(&user, WithId(1), UsePrimary())) {
+		assert.Equal(t, "hello primary", user.Nickname)
+		assert.EqualValues(t, 10, user.Age)
+	}
+
+	// 事务中的读写都使用主库
+	err = db.Tx(func(tx *Gormx) error {
+		var found User
+		if err := tx.FindOne(&found, WithId(2)); err != nil {
+			return err
+		}
+		assert.Equal(t, "hello insert", found.Nickname)
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+type flakyDialector struct {
+	gorm.Dialector
+	failures int
+	attempts int
+}
+
+var errNotReady = errors.New("database is not ready")
+
+func (d *flakyDialector) Initialize(db *gorm.DB) error {
+	d.attempts++
+	if d.attempts <= d.failures {
+		return errNotReady
+	}
+	return d.Dialector.Initialize(db)
+}
+
+func (suite *GormxTestSuite) TestConnectRetries() {
+	dialector := &flakyDialector{Dialector: suite.db.cfg.Dialector, failures: 2}
+	db, err := New(&Config{
+		Dialector:            dialector,
+		ConnectRetries:       3,
+		ConnectRetryInterval: time.Millisecond,
+	})
+	if suite.Assert().Nil(err) {
+		suite.Equal(3, dialector.attempts)
+		suite.Nil(db.Ping(context.Background()))
+		suite.Nil(db.Close())
+	}
+
+	dialector = &flakyDialector{Dialector: suite.db.cfg.Dialector, failures: 5}
+	_, err = New(&Config{
+		Dialector:            dialector,
+		ConnectRetries:       1,
+		ConnectRetryInterval: time.Millisecond,
+	})
+	suite.ErrorIs(err, errNotReady)
+	suite.Equal(2, dialector.attempts)
+}
+
+func TestAutoMigrate(t *testing.T) {
+	db, err := New(&Config{
+		Dialector: sqlite.Open("file::memory:"),
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	assert.False(t, db.Migrator().HasTable(&User{}))
+	if assert.Nil(t, db.AutoMigrate(&User{})) {
+		assert.True(t, db.Migrator().HasTable(&User{}))
+		assert.True(t, db.Migrator().HasTable("test_users"))
+	}
+}
+
+func TestPoolConfig(t *testing.T) {
+	for _, cfg := range []*Config{
+		{MaxIdleConn: 20, MaxOpenConn: 10},
+		{MaxOpenConn: -1},
+		{MaxLifetime: -1},
+	} {
+		cfg.Dialector = sqlite.Open("file::memory:")
+		_, err := New(cfg)
+		assert.NotNil(t, err)
+	}
+
+	db, err := New(&Config{
+		Dialector: sqlite.Open("file::memory:"),
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+	assert.Equal(t, defaultMaxOpenConn, db.Stats().MaxOpenConnections)
+}
+
+// namedDialector 修改 Dialector 的名称，用于测试不同数据库生成的 SQL
+type namedDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d namedDialector) Name() string {
+	return d.name
+}
+
+// timeoutDialector 记录 WithStatementTimeout 的参数，返回原来的 Dialector
+type timeoutDialector struct {
+	gorm.Dialector
+	timeouts *[]time.Duration
+}
+
+func (d timeoutDialector) WithStatementTimeout(timeout time.Duration) (gorm.Dialector, error) {
+	*d.timeouts = append(*d.timeouts, timeout)
+	return d.Dialector, nil
+}
+
+func TestStatementTimeout(t *testing.T) {
+	var timeouts []time.Duration
+	dialector := timeoutDialector{Dialector: sqlite.Open("file::memory:"), timeouts: &timeouts}
+	db, err := New(&Config{
+		Dialector:        dialector,
+		Replicas:         []gorm.Dialector{dialector},
+		StatementTimeout: time.Second,
+	})
+	if assert.Nil(t, err) {
+		defer db.Close()
+		assert.Equal(t, []time.Duration{time.Second, time.Second}, timeouts)
+	}
+
+	// 没有实现 StatementTimeoutDialector
+	_, err = New(&Config{Dialector: sqlite.Open("file::memory:"), StatementTimeout: time.Second})
+	assert.NotNil(t, err)
+
+	_, err = New(&Config{Dialector: dialector, StatementTimeout: -time.Second})
+	assert.NotNil(t, err)
+}
+
+func TestILike(t *testing.T) {
+	for _, c := range []struct {
+		dialect string
+		sql     string
+	}{
+		{"postgres", "WHERE `nickname` ILIKE \"%Hello%\""},
+		{"sqlite", "WHERE LOWER(`nickname`) LIKE LOWER(\"%Hello%\")"},
+		{"mysql", "WHERE LOWER(`nickname`) LIKE LOWER(\"%Hello%\")"},
+	} {
+		db, err := New(&Config{
+			Dialector: namedDialector{Dialector: sqlite.Open("file::memory:"), name: c.dialect},
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		sql := db.ToSQL(func(tx *Gormx) *gorm.DB {
+			return tx.BuildOptions(ILike("nickname", "%Hello%")).Find(&[]User{})
+		})
+		assert.Contains(t, sql, c.sql, c.dialect)
+		db.Close()
+	}
+}
+
+func TestDisableForeignKeyConstraint(t *testing.T) {
+	for _, disable := range []bool{false, true} {
+		db, err := New(&Config{
+			Dialector:                                sqlite.Open("file::memory:"),
+			DisableForeignKeyConstraintWhenMigrating: disable,
+		})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		if assert.Nil(t, db.AutoMigrate(&Order{}, &OrderItem{})) {
+			assert.True(t, db.Migrator().HasTable(&OrderItem{}))
+			assert.Equal(t, !disable, db.Migrator().HasConstraint(&Order{}, "Items"))
+		}
+		db.Close()
+	}
+}
+
+func (suite *GormxTestSuite) TestToSQL() {
+	sql := suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(WithId(1)).First(&User{})
+	})
+	suite.Contains(sql, "WHERE id=1")
+	suite.Contains(sql, "test_users")
+
+	sql = suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(Where("nickname = ?", "hello"), Pagination(2, 10)).Find(&[]User{})
+	})
+	suite.Contains(sql, "nickname = ")
+	suite.Contains(sql, "hello")
+	suite.Contains(sql, "LIMIT 10 OFFSET 10")
+}
+
+func (suite *GormxTestSuite) TestHint() {
+	sql := suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(Hint("USE INDEX (idx_age)"), Where("age > ?", 0)).Find(&[]User{})
+	})
+	suite.Contains(sql, "test_users")
+	suite.Contains(sql, " USE INDEX (idx_age) WHERE age > 0")
+
+	sql = suite.db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(Hint("USE INDEX (idx_age)"), Hint("IGNORE INDEX (idx_name)")).Find(&[]User{})
+	})
+	suite.Contains(sql, " USE INDEX (idx_age) IGNORE INDEX (idx_name)")
+}
+
+func (suite *GormxTestSuite) TestDryRun() {
+	db := suite.db.DryRun(WithId(1))
+	suite.Empty(db.Statement.SQL.String())
+
+	var users []User
+	stmt := db.Find(&users).Statement
+	suite.Contains(stmt.SQL.String(), "test_users")
+	suite.Contains(stmt.SQL.String(), "WHERE id=")
+	suite.Equal([]interface{}{int64(1)}, stmt.Vars)
+	suite.Empty(users)
+}
+
+func (suite *GormxTestSuite) TestBetween() {
+	var users []User
+	err := suite.db.FindMany(&users, Between("age", 0, 1))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Between("age", 1, 10))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(2, users[0].Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestLike() {
+	err := suite.db.Insert([]User{
+		{Nickname: "100% sure"},
+		{Nickname: "hello_world!"},
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	var users []User
+	err = suite.db.FindMany(&users, Like("nickname", "hello _"))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Contains("nickname", "%"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("100% sure", users[0].Nickname)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Contains("nickname", "o_w"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.Equal("hello_world!", users[0].Nickname)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, ILike("nickname", "HELLO _"))
+	if suite.Assert().Nil(err) {
+		suite.Equal(2, len(users))
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Contains("nickname", "d!"))
+	if suite.Assert().Nil(err) {
+		suite.Equal(1, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestIsNull() {
+	suite.initSoftUsers()
+	defer suite.dropSoftUsers()
+
+	if !suite.Assert().Nil(suite.db.Delete(&SoftUser{Id: 1})) {
+		return
+	}
+
+	var users []SoftUser
+	err := suite.db.FindMany(&users, Unscoped(), IsNotNull("deleted_at"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(1, users[0].Id)
+	}
+
+	users = nil
+	err = suite.db.FindMany(&users, Unscoped(), IsNull("deleted_at"))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(users)) {
+		suite.EqualValues(2, users[0].Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestSave() {
+	user := User{Id: 1, Nickname: "hello save", Age: 10}
+	n, err := suite.db.SaveN(&user)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, n)
+	}
+
+	// 记录不存在时会插入新记录
+	err = suite.db.Save(&User{Id: 100, Nickname: "hello save insert"})
+	if suite.Assert().Nil(err) {
+		var found User
+		err = suite.db.FindOne(&found, WithId(100))
+		if suite.Assert().Nil(err) {
+			suite.Equal("hello save insert", found.Nickname)
+		}
+	}
+
+	user = User{Nickname: "hello save new"}
+	n, err = suite.db.SaveN(&user)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, n)
+		suite.NotZero(user.Id)
+	}
+}
+
+func (suite *GormxTestSuite) TestRowsAffected() {
+	n, err := suite.db.InsertN([]User{{Nickname: "hello 2"}, {Nickname: "hello 3"}})
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, n)
+	}
+
+	n, err = suite.db.Model(&User{}).UpdateN("age", 7, Where("id <= ?", 3))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, n)
+	}
+
+	n, err = suite.db.Model(&User{}).UpdatesN(map[string]interface{}{"age": 8}, Where("age = ?", 7))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, n)
+	}
+
+	n, err = suite.db.Model(&User{}).UpdatesN(map[string]interface{}{"age": 9}, WithId(-1))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(0, n)
+	}
+
+	n, err = suite.db.DeleteN(&User{}, Where("age = ?", 8))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, n)
+	}
+}
+
+func (suite *GormxTestSuite) TestDeleteWhere() {
+	n, err := suite.db.DeleteWhere(&User{}, Where("age < ?", 5))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, n)
+	}
+
+	_, err = suite.db.DeleteWhere(&User{})
+	suite.ErrorIs(err, gorm.ErrMissingWhereClause)
+}
+
+func (suite *GormxTestSuite) TestPluckMany() {
+	var rows []struct {
+		Id       int64
+		Nickname string
+	}
+	err := suite.db.Model(&User{}).PluckMany([]string{"id", "nickname"}, &rows, OrderBy("id", false))
+	if suite.Assert().Nil(err) && suite.Equal(2, len(rows)) {
+		suite.EqualValues(1, rows[0].Id)
+		suite.Equal("hello 0", rows[0].Nickname)
+		suite.EqualValues(2, rows[1].Id)
+		suite.Equal("hello 1", rows[1].Nickname)
+	}
+
+	var maps []map[string]interface{}
+	err = suite.db.Model(&User{}).PluckMany([]string{"id", "nickname"}, &maps, WithId(1))
+	if suite.Assert().Nil(err) && suite.Equal(1, len(maps)) {
+		suite.Equal("hello 0", maps[0]["nickname"])
+		suite.NotContains(maps[0], "age")
+	}
+}
+
+func (suite *GormxTestSuite) TestCountDistinct() {
+	err := suite.db.Insert([]User{
+		{Nickname: "hello 2", Age: 1},
+		{Nickname: "hello 3", Age: 2},
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	total, err := suite.db.Model(&User{}).CountDistinct("age")
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(3, total)
+	}
+
+	total, err = suite.db.Model(&User{}).CountDistinct("age", Where("age > ?", 0))
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestRawCount() {
+	total, err := suite.db.RawCount("SELECT * FROM test_users")
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, total)
+	}
+
+	total, err = suite.db.RawCount("select * from test_users where age > ?;", 0)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, total)
+	}
+}
+
+func (suite *GormxTestSuite) TestScanMap() {
+	var rows []map[string]interface{}
+	err := suite.db.Raw("select id, nickname from test_users order by id").Scan(&rows)
+	if suite.Assert().Nil(err) && suite.Equal(2, len(rows)) {
+		suite.EqualValues(1, rows[0]["id"])
+		suite.Equal("hello 0", rows[0]["nickname"])
+		suite.Equal("hello 1", rows[1]["nickname"])
+	}
+
+	row := map[string]interface{}{}
+	err = suite.db.Raw("select nickname from test_users where id=?", 2).Scan(&row)
+	if suite.Assert().Nil(err) {
+		suite.Equal("hello 1", row["nickname"])
+	}
+}
+
+func (suite *GormxTestSuite) TestRawNamed() {
+	var user User
+	err := suite.db.Raw("select * from test_users where id = @id", sql.Named("id", 1)).FindOne(&user)
+	if suite.Assert().Nil(err) {
+		suite.Equal("hello 0", user.Nickname)
+	}
+
+	user = User{}
+	err = suite.db.Raw("select * from test_users where id = @id and nickname = @nickname", map[string]interface{}{
+		"id":       2,
+		"nickname": "hello 1",
+	}).FindOne(&user)
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(2, user.Id)
+	}
+}
+
+// slowQuery 返回一条执行时间较长的查询语句
+func slowQuery(db *Gormx) string {
+	switch db.DB().Dialector.Name() {
+	case "postgres":
+		return "select pg_sleep(3)"
+	case "mysql":
+		return "select sleep(3)"
+	default:
+		return "with recursive c(x) as (select 1 union all select x+1 from c where x < 100000000) select count(*) from c"
+	}
+}
+
+func (suite *GormxTestSuite) TestQueryTimeout() {
+	db, err := New(&Config{
+		Dialector:    suite.db.cfg.Dialector,
+		QueryTimeout: 50 * time.Millisecond,
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	var result int64
+	start := time.Now()
+	err = db.Raw(slowQuery(db)).Scan(&result)
+	suite.True(IsTimeout(err))
+	suite.Less(time.Since(start), time.Second)
+
+	var user User
+	suite.Nil(db.FindOne(&user, WithId(1)))
+
+	// Save 更新不到记录时会继续插入，插入时不能使用已经取消的上下文
+	suite.Nil(db.Save(&User{Id: 100, Nickname: "hello save"}))
+	var saved User
+	if suite.Assert().Nil(db.FindOne(&saved, WithId(100))) {
+		suite.Equal("hello save", saved.Nickname)
+	}
+
+	err = db.Tx(func(tx *Gormx) error {
+		if err := tx.Insert(&User{Nickname: "hello tx"}); err != nil {
+			return err
+		}
+		return tx.Model(&User{Id: 1}).Update("age", 10)
+	})
+	suite.Nil(err)
+}
+
+func (suite *GormxTestSuite) TestIsTimeout() {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	var user User
+	err := suite.db.WithContext(ctx).FindOne(&user, WithId(1))
+	suite.True(IsTimeout(err))
+	suite.False(IsNotFound(err))
+
+	suite.False(IsTimeout(suite.db.FindOne(&user, WithId(-1))))
+}
+
+func (suite *GormxTestSuite) TestRegisterCallback() {
+	err := suite.db.RegisterCreateCallback("test:set_age", CallbackBefore, func(db *gorm.DB) {
+		db.Statement.SetColumn("age", 100)
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+
+	user := User{Nickname: "hello callback"}
+	if suite.Assert().Nil(suite.db.Insert(&user)) {
+		var found User
+		suite.Nil(suite.db.FindOne(&found, WithId(user.Id)))
+		suite.EqualValues(100, found.Age)
+	}
+
+	suite.Nil(suite.db.RemoveCallback("test:set_age"))
+	user = User{Nickname: "hello no callback", Age: 1}
+	if suite.Assert().Nil(suite.db.Insert(&user)) {
+		var found User
+		suite.Nil(suite.db.FindOne(&found, WithId(user.Id)))
+		suite.EqualValues(1, found.Age)
+	}
+}
+
+func (suite *GormxTestSuite) TestDefaultScopes() {
+	db := suite.db.WithDefaultScopes(Tenant("age", 1))
+
+	var users []User
+	if suite.Assert().Nil(db.FindMany(&users)) && suite.Equal(1, len(users)) {
+		suite.Equal("hello 1", users[0].Nickname)
+	}
+
+	total, err := db.Model(&User{}).Count()
+	if suite.Assert().Nil(err) {
+		suite.EqualValues(1, total)
+	}
+
+	suite.Equal(ErrNoRowsAffected, db.Model(&User{}).Update("nickname", "hello tenant", WithId(1)))
+
+	users = nil
+	if suite.Assert().Nil(db.WithoutDefaultScopes().FindMany(&users)) {
+		suite.Equal(2, len(users))
+	}
+}
+
+func (suite *GormxTestSuite) TestNamingStrategy() {
+	type Account struct {
+		Id       int64
+		Nickname string
+	}
+
+	db, err := New(&Config{
+		Dialector:     suite.db.cfg.Dialector,
+		TablePrefix:   "app_",
+		SingularTable: true,
+	})
+	if !suite.Assert().Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	sql := db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(WithId(1)).Take(&Account{})
+	})
+	suite.Contains(sql, "app_account")
+	suite.NotContains(sql, "app_accounts")
+
+	// 实现了 TableName 方法的模型同样添加前缀
+	sql = db.ToSQL(func(tx *Gormx) *gorm.DB {
+		return tx.BuildOptions(WithId(1)).Take(&User{})
+	})
+	suite.Contains(sql, "app_test_users")
+
+	if !suite.Assert().Nil(db.AutoMigrate(&User{})) {
+		return
+	}
+	defer db.Exec("drop table app_test_users;")
+	suite.True(db.Migrator().HasTable("app_test_users"))
+
+	user := User{Nickname: "hello prefix", Age: 1}
+	if suite.Assert().Nil(db.Insert(&user)) {
+		var found User
+		suite.Nil(db.FindOne(&found, WithId(user.Id)))
+		suite.Equal("hello prefix", found.Nickname)
+		suite.Nil(db.Model(&found).Update("age", 2))
+		total, err := db.Model(&User{}).Count()
+		if suite.Assert().Nil(err) {
+			suite.EqualValues(1, total)
+		}
+	}
+	// Save 更新不到记录时会复制 Statement 再插入，不能重复添加前缀
+	suite.Nil(db.Save(&User{Id: 100, Nickname: "hello save"}))
+	suite.Nil(db.Delete(&User{Id: user.Id}))
+
+	// 通过 Table 指定的表名不添加前缀，原来的表不受影响
+	var users []User
+	if suite.Assert().Nil(db.FindMany(&users, Table("test_users"))) {
+		suite.Equal(2, len(users))
+	}
+	var prefixed []User
+	if suite.Assert().Nil(db.FindMany(&prefixed)) && suite.Equal(1, len(prefixed)) {
+		suite.EqualValues(100, prefixed[0].Id)
+	}
+}
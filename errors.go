@@ -0,0 +1,94 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// sqlStateError pgx、lib/pq 等驱动的错误都实现了该接口
+type sqlStateError interface {
+	SQLState() string
+}
+
+var (
+	duplicateStates   = []string{"23505"}
+	duplicateMessages = []string{
+		"Error 1062",                 // mysql
+		"SQLSTATE 23505",             // postgres(pgx)
+		"duplicate key value",        // postgres(lib/pq)
+		"UNIQUE constraint failed",   // sqlite
+		"PRIMARY KEY must be unique", // sqlite(旧版本)
+	}
+)
+
+var (
+	retryableStates   = []string{"40001", "40P01"}
+	retryableMessages = []string{
+		"Error 1213",     // mysql deadlock
+		"SQLSTATE 40001", // postgres serialization failure
+		"SQLSTATE 40P01", // postgres deadlock
+	}
+)
+
+var (
+	timeoutStates   = []string{"57014"}
+	timeoutMessages = []string{
+		"Error 3024",     // mysql max_execution_time
+		"SQLSTATE 57014", // postgres statement_timeout
+		"canceling statement due to statement timeout", // postgres(lib/pq)
+	}
+)
+
+// IsNotFound 判断是否为记录不存在的错误
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsDuplicate 判断是否为唯一键冲突的错误，支持 mysql、postgres、sqlite
+func IsDuplicate(err error) bool {
+	return matchError(err, duplicateStates, duplicateMessages)
+}
+
+// IsRetryable 判断是否为可以重试的死锁或序列化失败错误
+func IsRetryable(err error) bool {
+	return matchError(err, retryableStates, retryableMessages)
+}
+
+// IsTimeout 判断是否为超时或上下文取消的错误，包括 context.DeadlineExceeded、context.Canceled、网络超时以及数据库的语句超时
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return matchError(err, timeoutStates, timeoutMessages)
+}
+
+// matchError 优先根据 SQLSTATE 判断，驱动没有提供时再匹配错误信息
+func matchError(err error, states []string, messages []string) bool {
+	if err == nil {
+		return false
+	}
+
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		state := stateErr.SQLState()
+		for i := range states {
+			if state == states[i] {
+				return true
+			}
+		}
+	}
+
+	msg := err.Error()
+	for i := range messages {
+		if strings.Contains(msg, messages[i]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStateError struct {
+	state string
+}
+
+func (e *fakeStateError) Error() string {
+	return "fake error"
+}
+
+func (e *fakeStateError) SQLState() string {
+	return e.state
+}
+
+func TestIsDuplicate(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"mysql", errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'"), true},
+		{"mysql other", errors.New("Error 1146 (42S02): Table 'test.users' doesn't exist"), false},
+		{"postgres pgx", errors.New(`ERROR: duplicate key value violates unique constraint "test_users_pkey" (SQLSTATE 23505)`), true},
+		{"postgres pq", errors.New(`pq: duplicate key value violates unique constraint "test_users_pkey"`), true},
+		{"postgres state", &fakeStateError{state: "23505"}, true},
+		{"postgres other state", &fakeStateError{state: "23503"}, false},
+		{"sqlite", errors.New("UNIQUE constraint failed: test_users.id"), true},
+		{"wrapped", fmt.Errorf("insert user failed, %w", &fakeStateError{state: "23505"}), true},
+		{"other", errors.New("connection refused"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, IsDuplicate(c.err))
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"mysql deadlock", errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), true},
+		{"postgres serialization", errors.New("ERROR: could not serialize access due to concurrent update (SQLSTATE 40001)"), true},
+		{"postgres deadlock", &fakeStateError{state: "40P01"}, true},
+		{"duplicate", &fakeStateError{state: "23505"}, false},
+		{"other", errors.New("connection refused"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, IsRetryable(c.err))
+		})
+	}
+}
+
+type fakeNetError struct {
+	timeout bool
+}
+
+func (e *fakeNetError) Error() string   { return "fake net error" }
+func (e *fakeNetError) Timeout() bool   { return e.timeout }
+func (e *fakeNetError) Temporary() bool { return false }
+
+func TestIsTimeout(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", fmt.Errorf("query failed, %w", context.Canceled), true},
+		{"net timeout", &fakeNetError{timeout: true}, true},
+		{"net other", &fakeNetError{timeout: false}, false},
+		{"mysql", errors.New("Error 3024 (HY000): Query execution was interrupted, maximum statement execution time exceeded"), true},
+		{"postgres pgx", errors.New("ERROR: canceling statement due to statement timeout (SQLSTATE 57014)"), true},
+		{"postgres state", &fakeStateError{state: "57014"}, true},
+		{"other", errors.New("connection refused"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, IsTimeout(c.err))
+		})
+	}
+}
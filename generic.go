@@ -0,0 +1,64 @@
+package gormx
+
+// FindOneT 同 Gormx.FindOne，返回查询到的记录，不需要提前声明 dest
+func FindOneT[T any](db *Gormx, opts ...Option) (*T, error) {
+	var dest T
+	if err := db.FindOne(&dest, opts...); err != nil {
+		return nil, err
+	}
+	return &dest, nil
+}
+
+// FindManyT 同 Gormx.FindMany，返回查询到的记录，没有符合条件的记录时返回空切片
+func FindManyT[T any](db *Gormx, opts ...Option) ([]T, error) {
+	var dest []T
+	if err := db.FindMany(&dest, opts...); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// FindByIdsMapT 根据多个 id 查询，返回以 keyFn 的结果为键的 map，不存在的 id 不会出现在 map 中
+func FindByIdsMapT[T any](db *Gormx, ids []int64, keyFn func(*T) int64, opts ...Option) (map[int64]*T, error) {
+	opts = append(opts[:len(opts):len(opts)], WithIds(ids...))
+	items, err := FindManyT[T](db, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int64]*T, len(items))
+	for i := range items {
+		result[keyFn(&items[i])] = &items[i]
+	}
+	return result, nil
+}
+
+// Page 分页查询的结果，可以直接序列化后返回给调用方
+type Page[T any] struct {
+	Items      []T   `json:"items"`
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	Size       int   `json:"size"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// FindPageResult 分页查询，page、size 的处理与 Gormx.Pagination 一致，返回当前页的数据及分页信息
+func FindPageResult[T any](db *Gormx, page, size int, opts ...Option) (*Page[T], error) {
+	if page <= 0 {
+		page = 1
+	}
+	defaultSize, maxSize := db.cfg.pageSizes()
+	size = limitPageSize(size, defaultSize, maxSize)
+
+	var items []T
+	total, err := db.FindPage(&items, page, size, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Page[T]{
+		Items:      items,
+		Total:      total,
+		Page:       page,
+		Size:       size,
+		TotalPages: int((total + int64(size) - 1) / int64(size)),
+	}, nil
+}
@@ -0,0 +1,42 @@
+package gormx
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// newLogger 与 gorm 的 logger.Default 配置一致，只修改慢查询的阈值
+func newLogger(slowThreshold time.Duration) logger.Interface {
+	return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: slowThreshold,
+		LogLevel:      logger.Warn,
+		Colorful:      true,
+	})
+}
+
+// slowLogger 为自定义日志增加慢查询的警告日志
+type slowLogger struct {
+	logger.Interface
+	threshold time.Duration
+}
+
+func (l slowLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return slowLogger{
+		Interface: l.Interface.LogMode(level),
+		threshold: l.threshold,
+	}
+}
+
+func (l slowLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+	if elapsed := time.Since(begin); elapsed > l.threshold {
+		sql, rows := fc()
+		l.Interface.Warn(ctx, "SLOW SQL >= %v, elapsed: %v, rows: %d, sql: %s", l.threshold, elapsed, rows, sql)
+	}
+}
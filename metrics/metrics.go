@@ -0,0 +1,81 @@
+// Package metrics 以 gorm 插件的形式统计每种操作的执行次数和耗时
+// 通过 gormx.Config 的 Plugins 注册，不使用时不会引入 Prometheus
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lujin123/gormx"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const startKey = "gormx:metrics_start"
+
+// Metrics 记录每种操作的执行次数和耗时，同时实现了 gorm.Plugin 和 prometheus.Collector
+//
+//	m := metrics.New()
+//	db, err := gormx.New(&gormx.Config{Dialector: dialector, Plugins: []gorm.Plugin{m}})
+//	prometheus.MustRegister(m)
+type Metrics struct {
+	queries *prometheus.CounterVec
+	latency *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	return &Metrics{
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gormx",
+			Name:      "queries_total",
+			Help:      "Total number of executed statements.",
+		}, []string{"operation", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gormx",
+			Name:      "query_duration_seconds",
+			Help:      "Latency of executed statements in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+}
+
+func (m *Metrics) Name() string {
+	return "gormx:metrics"
+}
+
+// Initialize 注册 gorm 回调，统计每次操作的执行结果和耗时
+func (m *Metrics) Initialize(db *gorm.DB) error {
+	return gormx.RegisterAround(db, "metrics", func(string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			db.InstanceSet(startKey, time.Now())
+		}
+	}, func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			value, ok := db.InstanceGet(startKey)
+			if !ok {
+				return
+			}
+			start, ok := value.(time.Time)
+			if !ok {
+				return
+			}
+
+			status := "success"
+			if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				status = "error"
+			}
+			m.queries.WithLabelValues(operation, status).Inc()
+			m.latency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		}
+	})
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.queries.Describe(ch)
+	m.latency.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.queries.Collect(ch)
+	m.latency.Collect(ch)
+}
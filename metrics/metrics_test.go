@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/lujin123/gormx"
+	"github.com/lujin123/gormx/sqlitex"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	Id       int64
+	Nickname string
+}
+
+func TestMetrics(t *testing.T) {
+	memory, err := sqlitex.NewInMemory()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer memory.Close()
+	if !assert.Nil(t, memory.AutoMigrate(&User{})) || !assert.Nil(t, memory.Insert(&User{Nickname: "hello metrics"})) {
+		return
+	}
+
+	m := New()
+	db, err := gormx.New(&gormx.Config{
+		Dialector: memory.DB().Dialector,
+		Plugins:   []gorm.Plugin{m},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	var users []User
+	if assert.Nil(t, db.FindMany(&users)) {
+		assert.EqualValues(t, 1, testutil.ToFloat64(m.queries.WithLabelValues("query", "success")))
+		assert.Equal(t, 1, testutil.CollectAndCount(m, "gormx_query_duration_seconds"))
+	}
+
+	var user User
+	assert.True(t, gormx.IsNotFound(db.FindOne(&user, gormx.WithId(-1))))
+	assert.EqualValues(t, 2, testutil.ToFloat64(m.queries.WithLabelValues("query", "success")))
+
+	assert.NotNil(t, db.Exec("select * from not_exists"))
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.queries.WithLabelValues("raw", "error")))
+}
@@ -0,0 +1,45 @@
+package gormx
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// registerTablePrefix 注册 gorm 回调，为实现了 TableName 方法的模型添加表名前缀
+// gorm 的 NamingStrategy 只会为根据结构体名称生成的表名添加前缀，TableName 返回的表名会原样使用
+func registerTablePrefix(db *gorm.DB, prefix string) error {
+	return RegisterAround(db, "table_prefix", func(string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			stmt := db.Statement
+			// 通过 Table 指定了其他表名，或者 Save 等复制 Statement 时已经添加过前缀
+			if stmt.Schema == nil || stmt.TableExpr != nil || stmt.Table != stmt.Schema.Table {
+				return
+			}
+			if isTabler(stmt.Schema.ModelType) {
+				stmt.Table = prefix + stmt.Table
+			}
+		}
+	}, func(string) func(*gorm.DB) {
+		return func(*gorm.DB) {}
+	})
+}
+
+// isTabler 模型是否通过 TableName() 指定表名，TableName(schema.Namer) 可以自行使用 NamingStrategy，不需要处理
+func isTabler(modelType reflect.Type) bool {
+	model := reflect.New(modelType).Interface()
+	if _, ok := model.(schema.TablerWithNamer); ok {
+		return false
+	}
+	_, ok := model.(schema.Tabler)
+	return ok
+}
+
+// tablePrefix 返回 Config 中的表名前缀，通过 NewWithDB 创建时为空
+func (s *Gormx) tablePrefix() string {
+	if s.cfg == nil {
+		return ""
+	}
+	return s.cfg.TablePrefix
+}
@@ -1,8 +1,13 @@
 package gormx
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
 )
 
 type Option func(db *gorm.DB) *gorm.DB
@@ -18,48 +23,481 @@ func applyOptions(db *gorm.DB, opts ...Option) *gorm.DB {
 	return db.Scopes(scopes...)
 }
 
-func NoConflict(names ...string) Option {
+// If cond 为 true 时才应用 opt，用于动态构建查询条件
+func If(cond bool, opt Option) Option {
 	return func(db *gorm.DB) *gorm.DB {
-		var columns []clause.Column
-		if len(names) > 0 {
-			columns = make([]clause.Column, len(names))
-			for i := range names {
-				columns[i] = clause.Column{
-					Name: names[i],
-				}
-			}
+		if !cond {
+			return db
+		}
+		return opt(db)
+	}
+}
+
+// Compose 将多个 Option 组合成一个，按顺序应用，用于复用常用的条件组合
+func Compose(opts ...Option) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		for i := range opts {
+			db = opts[i](db)
 		}
+		return db
+	}
+}
+
+func NoConflict(names ...string) Option {
+	return func(db *gorm.DB) *gorm.DB {
 		return db.Clauses(clause.OnConflict{
-			Columns:   columns,
+			Columns:   toColumns(names),
 			DoNothing: true,
 		})
 	}
 }
 
+// OnConflictUpdate 冲突时更新指定字段，updateColumns 为空时更新所有字段
+func OnConflictUpdate(conflictColumns []string, updateColumns []string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(onConflictUpdate(conflictColumns, updateColumns))
+	}
+}
+
+// OnConflictUpdateWhere 同 OnConflictUpdate，只有满足 where 条件时才更新，mysql 不支持
+func OnConflictUpdateWhere(conflictColumns []string, updateColumns []string, where string, args ...interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		onConflict := onConflictUpdate(conflictColumns, updateColumns)
+		onConflict.Where = clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: where, Vars: args}}}
+		return db.Clauses(onConflict)
+	}
+}
+
+func onConflictUpdate(conflictColumns []string, updateColumns []string) clause.OnConflict {
+	onConflict := clause.OnConflict{
+		Columns: toColumns(conflictColumns),
+	}
+	if len(updateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	} else {
+		onConflict.UpdateAll = true
+	}
+	return onConflict
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Pagination 分页，size 默认为 20，最大为 100，需要自定义时使用 Gormx.Pagination
 func Pagination(page, size int) Option {
+	return pagination(page, size, defaultPageSize, maxPageSize)
+}
+
+func pagination(page, size, defaultSize, maxSize int) Option {
 	return func(db *gorm.DB) *gorm.DB {
 		if page <= 0 {
 			page = 1
 		}
-		switch {
-		case size > 100:
-			size = 100
-		case size <= 0:
-			size = 20
-		}
+		size = limitPageSize(size, defaultSize, maxSize)
 		offset := (page - 1) * size
 		return db.Offset(offset).Limit(size)
 	}
 }
 
+// Limit 限制返回的记录数，小于 0 时不限制并取消之前设置的 Limit，不受 MaxPageSize 的限制
+func Limit(n int) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		if n < 0 {
+			n = -1
+		}
+		return db.Limit(n)
+	}
+}
+
+// Offset 跳过的记录数，小于 0 时不跳过并取消之前设置的 Offset
+func Offset(n int) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		if n < 0 {
+			n = -1
+		}
+		return db.Offset(n)
+	}
+}
+
+// Keyset 游标分页，lastValue 为上一页最后一条记录的字段值，第一页传 nil
+// size 默认为 20，最大为 100，需要自定义时使用 Gormx.Keyset
+func Keyset(column string, lastValue interface{}, size int, desc bool) Option {
+	return keyset(column, lastValue, size, desc, defaultPageSize, maxPageSize)
+}
+
+func keyset(column string, lastValue interface{}, size int, desc bool, defaultSize, maxSize int) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		if lastValue != nil {
+			col := clause.Column{Name: column}
+			if desc {
+				db = db.Where(clause.Lt{Column: col, Value: lastValue})
+			} else {
+				db = db.Where(clause.Gt{Column: col, Value: lastValue})
+			}
+		}
+		return OrderBy(column, desc)(db).Limit(limitPageSize(size, defaultSize, maxSize))
+	}
+}
+
 func WithId(id int64) Option {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("id=?", id)
 	}
 }
 
+// Where 通用查询条件，支持 gorm Where 的所有形式，多个 Where 之间为 AND 关系
+func Where(query interface{}, args ...interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	}
+}
+
+// Tenant 多租户条件，只查询或修改 column 等于 id 的记录，可以通过 Gormx.WithDefaultScopes 应用到所有操作
+func Tenant(column string, id interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Eq{Column: clause.Column{Name: column}, Value: id})
+	}
+}
+
+// Or 与之前的条件为 OR 关系，需要与前面的条件整体分组时使用 WhereGroup
+func Or(query interface{}, args ...interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Or(query, args...)
+	}
+}
+
+// WhereGroup 将多个条件用 OR 连接并加上括号，与其他条件之间仍为 AND 关系
+// 每个 opt 中的多个条件之间为 AND 关系，opt 中只有 Where 等查询条件会生效
+func WhereGroup(opts ...Option) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		var exprs []clause.Expression
+		for i := range opts {
+			tx := opts[i](db.Session(&gorm.Session{NewDB: true}))
+			// 条件出错时不能忽略，否则会查询到所有记录
+			if tx.Error != nil && tx.Error != db.Error {
+				_ = db.AddError(tx.Error)
+				return db
+			}
+			if c, ok := tx.Statement.Clauses["WHERE"]; ok {
+				if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+					exprs = append(exprs, clause.And(where.Exprs...))
+				}
+			}
+		}
+		if len(exprs) == 0 {
+			return db
+		}
+		return db.Where(clause.Or(exprs...))
+	}
+}
+
+// WhereIn 字段值在给定集合中，集合为空时不匹配任何记录，values 也可以是 Gormx.SubQuery 构建的子查询
+func WhereIn(column string, values interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		if _, ok := values.(*gorm.DB); ok {
+			return db.Where(fmt.Sprintf("%s IN (?)", column), values)
+		}
+		if isEmptySlice(values) {
+			return db.Where("1 = 0")
+		}
+		return db.Where(fmt.Sprintf("%s IN ?", column), values)
+	}
+}
+
+// Lock 锁定查询的记录，strength 如 UPDATE、SHARE，options 如 NOWAIT、SKIP LOCKED，需要在事务中使用才有意义
+func Lock(strength string, options ...string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.Locking{Strength: strength, Options: strings.Join(options, " ")})
+	}
+}
+
+// ForUpdate SELECT ... FOR UPDATE
+func ForUpdate() Option {
+	return Lock("UPDATE")
+}
+
+// ForUpdateNoWait SELECT ... FOR UPDATE NOWAIT，记录已被锁定时立即返回错误
+func ForUpdateNoWait() Option {
+	return Lock("UPDATE", "NOWAIT")
+}
+
+// ForUpdateSkipLocked SELECT ... FOR UPDATE SKIP LOCKED，跳过已被锁定的记录，适用于任务队列
+func ForUpdateSkipLocked() Option {
+	return Lock("UPDATE", "SKIP LOCKED")
+}
+
+// Select 只查询指定的字段，用于 Updates 时只更新指定的字段，零值字段也会被更新
+func Select(columns ...string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Select(columns)
+	}
+}
+
+// Omit 写入时忽略指定的字段，可用于 Insert、Save、Updates
+func Omit(columns ...string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Omit(columns...)
+	}
+}
+
+// Preload 预加载关联数据，多个 Preload 可以叠加使用，args 中可以传入 Option 作为预加载的条件
+func Preload(query string, args ...interface{}) Option {
+	// gorm 只识别 func(*gorm.DB) *gorm.DB 类型的条件，在闭包外转换，避免共享的 Option 并发执行时修改同一个切片
+	conds := make([]interface{}, len(args))
+	for i := range args {
+		if opt, ok := args[i].(Option); ok {
+			conds[i] = (func(*gorm.DB) *gorm.DB)(opt)
+		} else {
+			conds[i] = args[i]
+		}
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Preload(query, conds...)
+	}
+}
+
+// FullSaveAssociations 保存时同时更新关联记录的所有字段，默认只会插入不存在的关联记录，可用于 Save、Updates
+func FullSaveAssociations() Option {
+	return func(db *gorm.DB) *gorm.DB {
+		// Initialized 使 gorm 立即复制 Statement 并指向新的会话，否则在 Option 中新建的会话无法开启默认事务
+		return db.Session(&gorm.Session{FullSaveAssociations: true, Initialized: true})
+	}
+}
+
+// Returning 写入后返回指定字段的值并映射到传入的 struct 或 slice，可用于 Insert、Updates、Delete，需要数据库支持 RETURNING
+func Returning(columns ...string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.Returning{Columns: toColumns(columns)})
+	}
+}
+
+// Hint 在 FROM 表名之后添加提示，如 mysql 的 USE INDEX (idx_name)、FORCE INDEX (idx_name)，多个 Hint 会依次叠加
+// 与 Joins 一起使用时提示会在 JOIN 之后，此时需要使用原生 SQL
+func Hint(hint string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(fromHints{hint})
+	}
+}
+
+// fromHints 通过 FROM 子句的 AfterExpression 添加提示
+type fromHints []string
+
+func (h fromHints) ModifyStatement(stmt *gorm.Statement) {
+	c := stmt.Clauses["FROM"]
+	if prev, ok := c.AfterExpression.(fromHints); ok {
+		h = append(prev[:len(prev):len(prev)], h...)
+	}
+	c.AfterExpression = h
+	stmt.Clauses["FROM"] = c
+}
+
+func (h fromHints) Build(builder clause.Builder) {
+	builder.WriteString(strings.Join(h, " "))
+}
+
+// Table 指定查询的表名，用于同一个模型对应多张表的场景，如按年分表的 orders_2024
+func Table(name string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Table(name)
+	}
+}
+
+// Joins 关联查询，多个 Joins 会依次叠加
+func Joins(query string, args ...interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Joins(query, args...)
+	}
+}
+
+// Distinct 去重查询，可以配合 Pluck 获取字段的唯一值
+func Distinct(columns ...string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		args := make([]interface{}, len(columns))
+		for i := range columns {
+			args[i] = columns[i]
+		}
+		return db.Distinct(args...)
+	}
+}
+
+// Unscoped 包含软删除的记录，用于 Delete 时会物理删除
+func Unscoped() Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+}
+
+// WithIds 根据多个 id 查询，ids 为空时不匹配任何记录
+func WithIds(ids ...int64) Option {
+	return WhereIn("id", ids)
+}
+
+// ByPrimaryKey 根据 model 的主键查询，适用于联合主键或主键不是 id 的表
+// keys 的键为字段名或列名，需要包含 model 的全部主键
+func ByPrimaryKey(model interface{}, keys map[string]interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		if len(keys) != len(stmt.Schema.PrimaryFields) {
+			_ = db.AddError(fmt.Errorf("%s has %d primary keys, got %d", stmt.Schema.Name, len(stmt.Schema.PrimaryFields), len(keys)))
+			return db
+		}
+
+		exprs := make([]clause.Expression, 0, len(keys))
+		for _, field := range stmt.Schema.PrimaryFields {
+			value, ok := keys[field.DBName]
+			if !ok {
+				value, ok = keys[field.Name]
+			}
+			if !ok {
+				_ = db.AddError(fmt.Errorf("primary key %s not found in keys", field.DBName))
+				return db
+			}
+			exprs = append(exprs, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+		}
+		return db.Where(clause.And(exprs...))
+	}
+}
+
+// UsePrimary 读操作强制使用主库，用于写后立即读的场景
+func UsePrimary() Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(dbresolver.Write)
+	}
+}
+
+// Between 范围查询，包含 low 和 high
+func Between(column string, low, high interface{}) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? BETWEEN ? AND ?", clause.Column{Name: column}, low, high)
+	}
+}
+
+// Like 模糊查询，pattern 中的 % 和 _ 作为通配符
+func Like(column, pattern string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? LIKE ?", clause.Column{Name: column}, pattern)
+	}
+}
+
+// ILike 不区分大小写的模糊查询，postgres 使用 ILIKE，其他数据库使用 LOWER(column) LIKE LOWER(pattern)
+func ILike(column, pattern string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		col := clause.Column{Name: column}
+		if db.Dialector.Name() == "postgres" {
+			return db.Where("? ILIKE ?", col, pattern)
+		}
+		return db.Where("LOWER(?) LIKE LOWER(?)", col, pattern)
+	}
+}
+
+// Contains 包含 substr 的模糊查询，substr 中的 % 和 _ 会被转义，只做普通字符匹配
+func Contains(column, substr string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? LIKE ? ESCAPE '"+likeEscape+"'", clause.Column{Name: column}, "%"+escapeLike(substr)+"%")
+	}
+}
+
+// IsNull 字段值为 NULL
+func IsNull(column string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? IS NULL", clause.Column{Name: column})
+	}
+}
+
+// IsNotNull 字段值不为 NULL
+func IsNotNull(column string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? IS NOT NULL", clause.Column{Name: column})
+	}
+}
+
 func Wildcard() Option {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Select("*")
 	}
 }
+
+// OrderBy 按字段排序，多次调用会按调用顺序追加排序条件
+func OrderBy(column string, desc bool) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: column},
+			Desc:   desc,
+		})
+	}
+}
+
+// OrderByLower 按字段的小写形式排序，即不区分大小写排序
+func OrderByLower(column string, desc bool) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: "LOWER(" + db.Statement.Quote(column) + ")", Raw: true},
+			Desc:   desc,
+		})
+	}
+}
+
+type OrderField struct {
+	Column string
+	Desc   bool
+}
+
+// OrderByFields 按多个字段排序
+func OrderByFields(fields ...OrderField) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		for i := range fields {
+			db = OrderBy(fields[i].Column, fields[i].Desc)(db)
+		}
+		return db
+	}
+}
+
+func isEmptySlice(values interface{}) bool {
+	if values == nil {
+		return true
+	}
+	rv := reflect.ValueOf(values)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+func limitPageSize(size, defaultSize, maxSize int) int {
+	switch {
+	case size > maxSize:
+		return maxSize
+	case size <= 0:
+		return defaultSize
+	}
+	return size
+}
+
+func toColumns(names []string) []clause.Column {
+	if len(names) == 0 {
+		return nil
+	}
+	columns := make([]clause.Column, len(names))
+	for i := range names {
+		columns[i] = clause.Column{
+			Name: names[i],
+		}
+	}
+	return columns
+}
+
+// likeEscape 不使用反斜杠作为转义字符，避免 mysql 中需要二次转义
+const likeEscape = "!"
+
+var likeReplacer = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
@@ -0,0 +1,69 @@
+// Package postgresx 包装 postgres 驱动的 Dialector，支持 gormx.Config 的 StatementTimeout
+// 单独放在子包中，不使用 postgres 时不会引入驱动
+package postgresx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Dialector 与 postgres.Dialector 相同，同时实现了 gormx.StatementTimeoutDialector
+type Dialector struct {
+	*postgres.Dialector
+}
+
+func Open(dsn string) gorm.Dialector {
+	return New(postgres.Config{DSN: dsn})
+}
+
+func New(config postgres.Config) gorm.Dialector {
+	return &Dialector{Dialector: postgres.New(config).(*postgres.Dialector)}
+}
+
+// WithStatementTimeout 在 DSN 中加上 statement_timeout 参数，由服务端在新建连接时设置
+// 通过 Conn 传入已有连接时无法修改连接参数，返回错误
+func (d *Dialector) WithStatementTimeout(timeout time.Duration) (gorm.Dialector, error) {
+	if d.Conn != nil {
+		return nil, errors.New("statement timeout is not supported when Conn is set")
+	}
+	dsn, err := StatementTimeoutDSN(d.DSN, timeout)
+	if err != nil {
+		return nil, err
+	}
+	config := *d.Config
+	config.DSN = dsn
+	return New(config), nil
+}
+
+// StatementTimeoutDSN 在 DSN 中加上 statement_timeout 参数，单位为毫秒，不足 1 毫秒的部分向上取整
+// statement_timeout 为 0 表示不超时，所以 timeout 必须大于 0
+func StatementTimeoutDSN(dsn string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return "", fmt.Errorf("statement timeout %s should be positive", timeout)
+	}
+	ms := timeout / time.Millisecond
+	if timeout%time.Millisecond != 0 {
+		ms++
+	}
+	return withDSNParam(dsn, "statement_timeout", strconv.FormatInt(int64(ms), 10)), nil
+}
+
+// withDSNParam 添加连接参数，支持 postgres://user@host/db 和 host=localhost user=postgres 两种格式
+func withDSNParam(dsn, key, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			query := u.Query()
+			query.Set(key, value)
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+	}
+	return strings.TrimSpace(dsn) + " " + key + "=" + value
+}
@@ -0,0 +1,67 @@
+package postgresx
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lujin123/gormx"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+)
+
+func TestStatementTimeoutDSN(t *testing.T) {
+	for _, c := range []struct {
+		dsn     string
+		timeout time.Duration
+		want    string
+	}{
+		{"host=localhost user=postgres", 1500 * time.Millisecond, "host=localhost user=postgres statement_timeout=1500"},
+		{"postgres://postgres@localhost/test?sslmode=disable", 1500 * time.Millisecond, "postgres://postgres@localhost/test?sslmode=disable&statement_timeout=1500"},
+		// 不足 1 毫秒的部分向上取整，不能变成表示不超时的 0
+		{"host=localhost", time.Microsecond, "host=localhost statement_timeout=1"},
+		{"host=localhost", 1500 * time.Microsecond, "host=localhost statement_timeout=2"},
+	} {
+		dsn, err := StatementTimeoutDSN(c.dsn, c.timeout)
+		if assert.Nil(t, err) {
+			assert.Equal(t, c.want, dsn)
+		}
+	}
+
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		_, err := StatementTimeoutDSN("host=localhost", timeout)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestWithStatementTimeout(t *testing.T) {
+	dialector := Open("host=localhost user=postgres")
+	assert.Equal(t, "postgres", dialector.Name())
+
+	d, ok := dialector.(gormx.StatementTimeoutDialector)
+	if !assert.True(t, ok) {
+		return
+	}
+	result, err := d.WithStatementTimeout(1500 * time.Millisecond)
+	if !assert.Nil(t, err) {
+		return
+	}
+	// 驱动解析 DSN 后作为连接参数发送给服务端
+	conf, err := pgx.ParseConfig(result.(*Dialector).DSN)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1500", conf.RuntimeParams["statement_timeout"])
+	}
+	assert.Equal(t, "host=localhost user=postgres", dialector.(*Dialector).DSN)
+
+	_, err = d.WithStatementTimeout(0)
+	assert.NotNil(t, err)
+
+	sqlDb, err := sql.Open("pgx", "host=localhost")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer sqlDb.Close()
+	_, err = New(postgres.Config{Conn: sqlDb}).(*Dialector).WithStatementTimeout(time.Second)
+	assert.NotNil(t, err)
+}
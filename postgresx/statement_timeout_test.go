@@ -0,0 +1,39 @@
+//go:build postgres
+
+package postgresx
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lujin123/gormx"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStatementTimeout 需要 postgres 服务端，通过 GORMX_POSTGRES_DSN 指定连接
+//
+//	GORMX_POSTGRES_DSN="host=localhost user=postgres" go test -tags postgres ./postgresx
+func TestStatementTimeout(t *testing.T) {
+	dsn := os.Getenv("GORMX_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GORMX_POSTGRES_DSN is not set")
+	}
+
+	db, err := gormx.New(&gormx.Config{
+		Dialector:        Open(dsn),
+		StatementTimeout: 100 * time.Millisecond,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	// 没有设置 QueryTimeout，语句由服务端取消
+	var result interface{}
+	start := time.Now()
+	err = db.Raw("select pg_sleep(3)").Scan(&result)
+	assert.True(t, gormx.IsTimeout(err))
+	assert.Contains(t, err.Error(), "statement timeout")
+	assert.Less(t, time.Since(start), time.Second)
+}
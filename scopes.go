@@ -0,0 +1,39 @@
+package gormx
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// scopeRegistry 命名的 Option，同一个 New 创建的 Gormx 及其克隆对象共享
+type scopeRegistry struct {
+	mu     sync.RWMutex
+	scopes map[string]Option
+}
+
+func newScopeRegistry() *scopeRegistry {
+	return &scopeRegistry{scopes: make(map[string]Option)}
+}
+
+// RegisterScope 注册命名的 Option，之后可以通过 UseScope 使用，同名时会覆盖之前的 Option
+func (s *Gormx) RegisterScope(name string, opt Option) {
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	s.registry.scopes[name] = opt
+}
+
+// UseScope 返回 RegisterScope 注册的 Option，name 没有注册时执行会返回错误
+func (s *Gormx) UseScope(name string) Option {
+	return func(db *gorm.DB) *gorm.DB {
+		s.registry.mu.RLock()
+		opt, ok := s.registry.scopes[name]
+		s.registry.mu.RUnlock()
+		if !ok {
+			_ = db.AddError(fmt.Errorf("scope %s not registered", name))
+			return db
+		}
+		return opt(db)
+	}
+}
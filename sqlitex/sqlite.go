@@ -0,0 +1,29 @@
+// Package sqlitex 使用 sqlite 创建 gormx.Gormx，主要用于测试
+// sqlite 驱动依赖 cgo，所以单独放在子包中，不使用时不会引入
+package sqlitex
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/lujin123/gormx"
+	"gorm.io/driver/sqlite"
+)
+
+var memorySeq int64
+
+// NewSQLite 使用 sqlite 创建数据库对象，path 为数据库文件的路径
+// sqlite 同一时间只能有一个写入，连接池限制为一个连接，且连接不会过期
+func NewSQLite(path string) (*gormx.Gormx, error) {
+	return gormx.New(&gormx.Config{
+		Dialector:   sqlite.Open(path),
+		MaxIdleConn: 1,
+		MaxOpenConn: 1,
+	})
+}
+
+// NewInMemory 创建 sqlite 内存数据库，每次调用都是一个独立的数据库，所有连接关闭后数据不会保留
+// 使用相同的 Dialector 创建的其他 gormx.Gormx 连接的是同一个数据库
+func NewInMemory() (*gormx.Gormx, error) {
+	return NewSQLite(fmt.Sprintf("file:gormx_%d?mode=memory&cache=shared", atomic.AddInt64(&memorySeq, 1)))
+}
@@ -0,0 +1,91 @@
+package sqlitex
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lujin123/gormx"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	Id       int64
+	Nickname string
+	Age      int64
+}
+
+func TestNewInMemory(t *testing.T) {
+	db, err := NewInMemory()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+	if !assert.Nil(t, db.AutoMigrate(&User{})) {
+		return
+	}
+
+	user := User{Nickname: "hello memory", Age: 18}
+	if !assert.Nil(t, db.Insert(&user)) {
+		return
+	}
+	assert.EqualValues(t, 1, user.Id)
+
+	var found User
+	if assert.Nil(t, db.FindOne(&found, gormx.WithId(user.Id))) {
+		assert.Equal(t, "hello memory", found.Nickname)
+	}
+
+	assert.Nil(t, db.Model(&User{Id: user.Id}).Update("age", 20))
+	err = db.Tx(func(tx *gormx.Gormx) error {
+		return tx.Insert(&User{Nickname: "hello tx"})
+	})
+	assert.Nil(t, err)
+
+	var users []User
+	if assert.Nil(t, db.FindMany(&users, gormx.OrderBy("id", false))) && assert.Equal(t, 2, len(users)) {
+		assert.EqualValues(t, 20, users[0].Age)
+		assert.Equal(t, "hello tx", users[1].Nickname)
+	}
+
+	assert.Nil(t, db.Delete(&User{Id: user.Id}))
+	total, err := db.Model(&User{}).Count()
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, 1, total)
+	}
+
+	// 相同的 Dialector 连接的是同一个数据库
+	same, err := gormx.New(&gormx.Config{Dialector: db.DB().Dialector})
+	if assert.Nil(t, err) {
+		defer same.Close()
+		assert.True(t, same.Migrator().HasTable(&User{}))
+	}
+
+	// 每次调用都是独立的数据库
+	other, err := NewInMemory()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer other.Close()
+	assert.False(t, other.Migrator().HasTable(&User{}))
+}
+
+func TestNewSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gormx.db")
+	db, err := NewSQLite(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, db.AutoMigrate(&User{}))
+	assert.Nil(t, db.Insert(&User{Nickname: "hello file"}))
+	assert.Nil(t, db.Close())
+
+	db, err = NewSQLite(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+	var user User
+	if assert.Nil(t, db.FindOne(&user)) {
+		assert.Equal(t, "hello file", user.Nickname)
+	}
+}
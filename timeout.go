@@ -0,0 +1,96 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	timeoutCancelKey  = "gormx:timeout_cancel"
+	timeoutContextKey = "gormx:timeout_context"
+)
+
+// registerQueryTimeout 注册 gorm 回调，上下文没有设置截止时间时使用 timeout 作为超时时间
+// Row、Rows 返回后还需要读取数据，不能在回调结束时取消上下文，由 Gormx.rowsTimeout 在读取完成后取消
+func registerQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	return RegisterAround(db, "timeout", func(operation string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			if operation == "row" {
+				return
+			}
+			ctx := db.Statement.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if _, ok := ctx.Deadline(); ok {
+				return
+			}
+			timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+			db.InstanceSet(timeoutContextKey, db.Statement.Context)
+			db.InstanceSet(timeoutCancelKey, cancel)
+			db.Statement.Context = timeoutCtx
+		}
+	}, func(string) func(*gorm.DB) {
+		return func(db *gorm.DB) {
+			if value, ok := db.InstanceGet(timeoutCancelKey); ok {
+				if cancel, ok := value.(context.CancelFunc); ok {
+					cancel()
+				}
+			}
+			// 恢复原来的上下文，Save 更新失败后会复制 Statement 再执行插入，不能使用已经取消的上下文
+			if value, ok := db.InstanceGet(timeoutContextKey); ok {
+				ctx, _ := value.(context.Context)
+				db.Statement.Context = ctx
+			}
+		}
+	})
+}
+
+// rowsTimeout 为通过 Row、Rows 读取数据的操作设置超时时间，读取完成后需要调用返回的 cancel
+func (s *Gormx) rowsTimeout(db *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	if s.cfg == nil || s.cfg.QueryTimeout <= 0 {
+		return db, func() {}
+	}
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return db, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.QueryTimeout)
+	return db.WithContext(ctx), cancel
+}
+
+// scan 同 gorm 的 Scan，设置了 QueryTimeout 时同样会超时
+func (s *Gormx) scan(db *gorm.DB, dest interface{}) error {
+	db, cancel := s.rowsTimeout(db)
+	defer cancel()
+	if err := db.Scan(dest).Error; err != nil {
+		return err
+	}
+	// gorm 的 Scan 不检查 rows.Err()，读取时超时或者上下文被取消不会返回错误
+	if ctx := db.Statement.Context; ctx != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// StatementTimeoutDialector 支持在新建连接时设置服务端语句超时时间的 Dialector，例如 postgresx.Open 创建的 Dialector
+type StatementTimeoutDialector interface {
+	gorm.Dialector
+	// WithStatementTimeout 返回新的 Dialector，通过它创建的连接都会设置 timeout，不修改原来的 Dialector
+	WithStatementTimeout(timeout time.Duration) (gorm.Dialector, error)
+}
+
+// withStatementTimeout 通过 StatementTimeoutDialector 设置语句超时时间，没有实现时返回错误
+func withStatementTimeout(dialector gorm.Dialector, timeout time.Duration) (gorm.Dialector, error) {
+	d, ok := dialector.(StatementTimeoutDialector)
+	if !ok {
+		return nil, fmt.Errorf("dialector %s does not support statement timeout", dialector.Name())
+	}
+	return d.WithStatementTimeout(timeout)
+}
@@ -0,0 +1,87 @@
+// Package tracing 以 gorm 插件的形式为每次操作创建 OpenTelemetry span
+// 通过 gormx.Config 的 Plugins 注册，不使用时不会引入 OpenTelemetry
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lujin123/gormx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	tracerName = "github.com/lujin123/gormx"
+	spanKey    = "gormx:tracing_span"
+	contextKey = "gormx:tracing_context"
+)
+
+// Tracing 每次操作创建一个 span，记录 SQL、表名、影响行数和错误，需要通过 WithContext 传递上下文
+//
+//	db, err := gormx.New(&gormx.Config{Dialector: dialector, Plugins: []gorm.Plugin{tracing.New(nil)}})
+type Tracing struct {
+	tracer trace.Tracer
+}
+
+// New 创建链路追踪插件，tp 为 nil 时使用 otel.GetTracerProvider()
+func New(tp trace.TracerProvider) *Tracing {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracing{tracer: tp.Tracer(tracerName)}
+}
+
+func (t *Tracing) Name() string {
+	return "gormx:tracing"
+}
+
+// Initialize 注册 gorm 回调，在每次操作的前后创建和结束 span
+func (t *Tracing) Initialize(db *gorm.DB) error {
+	return gormx.RegisterAround(db, "tracing", func(operation string) func(*gorm.DB) {
+		return t.startSpan(operation)
+	}, func(string) func(*gorm.DB) {
+		return endSpan
+	})
+}
+
+func (t *Tracing) startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := t.tracer.Start(db.Statement.Context, "gormx."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		db.InstanceSet(contextKey, db.Statement.Context)
+		db.InstanceSet(spanKey, span)
+		db.Statement.Context = ctx
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	// 恢复原来的上下文，Save 更新失败后会复制 Statement 再执行插入，不能使用已经结束的 span 作为父 span
+	if value, ok := db.InstanceGet(contextKey); ok {
+		ctx, _ := value.(context.Context)
+		db.Statement.Context = ctx
+	}
+
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.system", db.Dialector.Name()),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lujin123/gormx"
+	"github.com/lujin123/gormx/sqlitex"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	Id       int64
+	Nickname string
+}
+
+func TestTracing(t *testing.T) {
+	memory, err := sqlitex.NewInMemory()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer memory.Close()
+	if !assert.Nil(t, memory.AutoMigrate(&User{})) || !assert.Nil(t, memory.Insert(&User{Nickname: "hello tracing"})) {
+		return
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	db, err := gormx.New(&gormx.Config{
+		Dialector: memory.DB().Dialector,
+		Plugins:   []gorm.Plugin{New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	var user User
+	err = db.WithContext(context.Background()).FindOne(&user, gormx.WithId(1))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	spans := recorder.Ended()
+	if assert.Equal(t, 1, len(spans)) {
+		assert.Equal(t, "gormx.query", spans[0].Name())
+		attrs := make(map[string]interface{})
+		for _, attr := range spans[0].Attributes() {
+			attrs[string(attr.Key)] = attr.Value.AsInterface()
+		}
+		assert.Equal(t, "users", attrs["db.sql.table"])
+		assert.EqualValues(t, 1, attrs["db.rows_affected"])
+		assert.Contains(t, attrs["db.statement"], "users")
+	}
+
+	var missing User
+	err = db.FindOne(&missing, gormx.WithId(-1))
+	assert.True(t, gormx.IsNotFound(err))
+	spans = recorder.Ended()
+	if assert.Equal(t, 2, len(spans)) {
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	}
+
+	assert.NotNil(t, db.Exec("select * from not_exists"))
+	spans = recorder.Ended()
+	if assert.Equal(t, 3, len(spans)) {
+		assert.Equal(t, "gormx.raw", spans[2].Name())
+		assert.Equal(t, codes.Error, spans[2].Status().Code)
+	}
+}
+
+func TestTracingSaveFallback(t *testing.T) {
+	memory, err := sqlitex.NewInMemory()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer memory.Close()
+	if !assert.Nil(t, memory.AutoMigrate(&User{})) {
+		return
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	db, err := gormx.New(&gormx.Config{
+		Dialector: memory.DB().Dialector,
+		Plugins:   []gorm.Plugin{New(tp)},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	// 主键不存在时 Save 先更新再插入，两个 span 都应该是 root 的子 span
+	ctx, root := tp.Tracer("test").Start(context.Background(), "root")
+	if !assert.Nil(t, db.WithContext(ctx).Save(&User{Id: 100, Nickname: "hello save"})) {
+		return
+	}
+	root.End()
+
+	// 更新、查询是否存在、插入，最后是 root
+	spans := recorder.Ended()
+	if assert.Equal(t, 4, len(spans)) {
+		assert.Equal(t, "gormx.update", spans[0].Name())
+		assert.Equal(t, "gormx.query", spans[1].Name())
+		assert.Equal(t, "gormx.create", spans[2].Name())
+		for _, span := range spans[:3] {
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+}